@@ -0,0 +1,37 @@
+package ffprobe
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// ProbeReaders probes all readers using ProbeReaderWithOptions, running at most concurrency ffprobe processes at the
+// same time. A concurrency of zero or less means one process at a time. The returned data and errors are positional,
+// the data and error at index i belong to the reader at index i.
+// This function takes a context to allow killing the ffprobe processes if they take too long or in case of shutdown.
+func ProbeReaders(ctx context.Context, readers []io.Reader, concurrency int, opts ...Option) ([]*ProbeData, []error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	results := make([]*ProbeData, len(readers))
+	errs := make([]error, len(readers))
+
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, concurrency)
+	for i := range readers {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-semaphore
+				wg.Done()
+			}()
+			results[i], errs[i] = ProbeReaderWithOptions(ctx, readers[i], opts...)
+		}(i)
+	}
+	wg.Wait()
+
+	return results, errs
+}
@@ -0,0 +1,53 @@
+package ffprobe
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Cache is used by ProbeURLCached to store the data of earlier probes, so they don't have to be repeated.
+// The package does not provide an implementation, it has to be safe for concurrent use if ProbeURLCached is
+// called concurrently.
+type Cache interface {
+	// Get returns the cached data for the key, and whether it was found
+	Get(key string) (*ProbeData, bool)
+	// Set stores the data for the key
+	Set(key string, data *ProbeData)
+}
+
+// ProbeURLCached works like ProbeURLWithOptions, but returns the data from the cache when it was probed before.
+// The key identifies the media file in the cache. When the key is empty, the fileURL is assumed to be a local path
+// and a key based on its path, modification time and size is used, see FileCacheKey.
+// Only successful probes are stored in the cache.
+func ProbeURLCached(ctx context.Context, cache Cache, key, fileURL string, opts ...Option) (data *ProbeData, err error) {
+	if key == "" {
+		key, err = FileCacheKey(fileURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, found := cache.Get(key)
+	if found {
+		return data, nil
+	}
+
+	data, err = ProbeURLWithOptions(ctx, fileURL, opts...)
+	if err != nil {
+		return data, err
+	}
+
+	cache.Set(key, data)
+	return data, nil
+}
+
+// FileCacheKey returns a cache key for a local file based on its path, modification time and size, so that the key
+// changes when the file is modified.
+func FileCacheKey(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("error getting file info: %w", err)
+	}
+	return fmt.Sprintf("%s:%d:%d", path, info.ModTime().UnixNano(), info.Size()), nil
+}
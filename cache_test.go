@@ -0,0 +1,50 @@
+package ffprobe
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+type mapCache map[string]*ProbeData
+
+func (c mapCache) Get(key string) (*ProbeData, bool) {
+	data, found := c[key]
+	return data, found
+}
+
+func (c mapCache) Set(key string, data *ProbeData) {
+	c[key] = data
+}
+
+func Test_ProbeURLCached(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	cache := mapCache{}
+	data, err := ProbeURLCached(ctx, cache, "", testPath)
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	validateData(t, data)
+
+	if len(cache) != 1 {
+		t.Fatalf("Expected data to be cached, got %d entries", len(cache))
+	}
+
+	key, err := FileCacheKey(testPath)
+	if err != nil {
+		t.Fatalf("Error getting cache key: %v", err)
+	}
+	cached, found := cache.Get(key)
+	if !found || cached != data {
+		t.Fatalf("Expected data to be cached under the file key")
+	}
+
+	// A cache hit must not run ffprobe, so use a path that would fail
+	cache.Set("cached", data)
+	cached, err = ProbeURLCached(ctx, cache, "cached", testPathError)
+	if err != nil || cached != data {
+		t.Errorf("Expected cached data to be returned, got error: %v", err)
+	}
+}
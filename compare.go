@@ -0,0 +1,107 @@
+package ffprobe
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MediaDiff describes the differences between a source media file and a destination, such as a transcode of it.
+type MediaDiff struct {
+	// DurationDelta is the duration of the destination minus the duration of the source
+	DurationDelta time.Duration
+	// SrcWidth and SrcHeight are the display dimensions of the first video stream of the source, DstWidth and
+	// DstHeight those of the destination. They are zero when there is no video stream.
+	SrcWidth, SrcHeight int
+	DstWidth, DstHeight int
+	// CodecChanges lists the streams of which the codec differs, streams are matched by their position among the
+	// streams of the same type.
+	CodecChanges []CodecChange
+	// StreamCountChanges holds the number of streams of the destination minus that of the source, for every stream
+	// type of which the number of streams differs.
+	StreamCountChanges map[StreamType]int
+}
+
+// CodecChange describes a stream of which the codec differs between the source and the destination
+type CodecChange struct {
+	// Type is the type of the stream
+	Type StreamType
+	// Position is the position of the stream among the streams of the same type, starting at 0
+	Position int
+	// Src and Dst are the codec names of the stream in the source and the destination
+	Src, Dst string
+}
+
+// diffStreamTypes are the stream types compared by Diff
+var diffStreamTypes = []StreamType{StreamVideo, StreamAudio, StreamSubtitle, StreamData, StreamAttachment}
+
+// ResolutionChanged returns whether the display dimensions of the first video stream differ
+func (d *MediaDiff) ResolutionChanged() bool {
+	return d.SrcWidth != d.DstWidth || d.SrcHeight != d.DstHeight
+}
+
+// Preserved returns whether the destination preserved the source: it has the same number of streams of every type,
+// the same resolution and a duration that differs at most tolerance. Codec changes are expected when transcoding
+// and are not considered.
+func (d *MediaDiff) Preserved(tolerance time.Duration) bool {
+	delta := d.DurationDelta
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta <= tolerance && !d.ResolutionChanged() && len(d.StreamCountChanges) == 0
+}
+
+// Diff compares the probe data of a source and a destination media file, see MediaDiff.
+func Diff(src, dst *ProbeData) *MediaDiff {
+	diff := &MediaDiff{
+		DurationDelta:      mediaDuration(dst) - mediaDuration(src),
+		StreamCountChanges: map[StreamType]int{},
+	}
+	if s := src.FirstVideoStream(); s != nil {
+		diff.SrcWidth, diff.SrcHeight = s.DisplayDimensions()
+	}
+	if s := dst.FirstVideoStream(); s != nil {
+		diff.DstWidth, diff.DstHeight = s.DisplayDimensions()
+	}
+
+	for _, streamType := range diffStreamTypes {
+		srcStreams, dstStreams := src.StreamType(streamType), dst.StreamType(streamType)
+		if len(srcStreams) != len(dstStreams) {
+			diff.StreamCountChanges[streamType] = len(dstStreams) - len(srcStreams)
+		}
+		for i := 0; i < len(srcStreams) && i < len(dstStreams); i++ {
+			if srcStreams[i].CodecName != dstStreams[i].CodecName {
+				diff.CodecChanges = append(diff.CodecChanges, CodecChange{
+					Type:     streamType,
+					Position: i,
+					Src:      srcStreams[i].CodecName,
+					Dst:      dstStreams[i].CodecName,
+				})
+			}
+		}
+	}
+	return diff
+}
+
+// mediaDuration returns the duration of the container, or the duration of the longest stream when it has none
+func mediaDuration(p *ProbeData) time.Duration {
+	if p.Format != nil && p.Format.DurationSeconds > 0 {
+		return p.Format.Duration()
+	}
+	return p.LongestStreamDuration()
+}
+
+// CompareMedia probes the source and destination media files using ProbeURLWithOptions and compares them, e.g. to
+// validate that a transcode preserved what it should. See Diff and MediaDiff.
+// This function takes a context to allow killing the ffprobe processes if they take too long or in case of shutdown.
+func CompareMedia(ctx context.Context, srcPath, dstPath string, opts ...Option) (*MediaDiff, error) {
+	src, err := ProbeURLWithOptions(ctx, srcPath, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error probing source: %w", err)
+	}
+	dst, err := ProbeURLWithOptions(ctx, dstPath, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error probing destination: %w", err)
+	}
+	return Diff(src, dst), nil
+}
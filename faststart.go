@@ -0,0 +1,67 @@
+package ffprobe
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// IsFastStart returns whether the media file is an MP4 file with its moov atom before the media data, which allows
+// playback to start before the whole file is downloaded. ffprobe does not report the position of the moov atom, so
+// the top level boxes of the file are read from disk. False is returned when the file is not an MP4 or MOV file, was
+// not probed by a local path or its boxes could not be read.
+func (p *ProbeData) IsFastStart() bool {
+	if p.Format == nil || !strings.Contains(p.Format.FormatName, "mp4") || !isLocalPath(p.Format.Filename) {
+		return false
+	}
+
+	file, err := os.Open(p.Format.Filename)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	moovFirst, err := mp4MoovFirst(file)
+	return err == nil && moovFirst
+}
+
+// mp4MoovFirst walks the top level boxes of an MP4 file and returns whether the moov box comes before the mdat box
+func mp4MoovFirst(r io.ReadSeeker) (bool, error) {
+	header := make([]byte, 16)
+	for {
+		_, err := io.ReadFull(r, header[:8])
+		if err != nil {
+			return false, err
+		}
+		size := int64(binary.BigEndian.Uint32(header[:4]))
+		headerSize := int64(8)
+
+		switch string(header[4:8]) {
+		case "moov":
+			return true, nil
+		case "mdat", "moof":
+			return false, nil
+		}
+
+		switch size {
+		case 0:
+			// The box extends to the end of the file
+			return false, io.EOF
+		case 1:
+			// The actual size is stored as a 64 bit integer after the type
+			if _, err = io.ReadFull(r, header[8:16]); err != nil {
+				return false, err
+			}
+			size = int64(binary.BigEndian.Uint64(header[8:16]))
+			headerSize = 16
+		}
+		if size < headerSize {
+			return false, errors.New("invalid MP4 box size")
+		}
+		if _, err = r.Seek(size-headerSize, io.SeekCurrent); err != nil {
+			return false, err
+		}
+	}
+}
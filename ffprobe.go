@@ -4,88 +4,662 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 var binPath = "ffprobe"
 
+var (
+	defaultArgsMu sync.RWMutex
+	defaultArgs   []string
+)
+
+// ErrNonJSONOutputFormat is returned when the extra ffprobe options select an output format other than JSON,
+// which cannot be parsed into the ProbeData.
+var ErrNonJSONOutputFormat = errors.New("go-ffprobe requires JSON output; remove -of flag")
+
+// ErrOutputFormatArg is returned by ProbeRaw when the default or extra ffprobe options select an output format, which
+// would override the requested output format.
+var ErrOutputFormatArg = errors.New("output format is set by the outputFormat parameter; remove -of flag")
+
+// ErrNicenessUnsupported is returned when WithNiceness is used on a platform that does not support process niceness,
+// such as Windows.
+var ErrNicenessUnsupported = errors.New("process niceness is not supported on this platform")
+
+// ErrOutputTooLarge is returned when the output of ffprobe exceeds the maximum size, see WithMaxOutputBytes.
+var ErrOutputTooLarge = errors.New("ffprobe output exceeds maximum size")
+
+// ErrInputSchemeNotAllowed is returned when the protocol of the input is not allowed, see WithAllowedInputSchemes.
+var ErrInputSchemeNotAllowed = errors.New("input scheme not allowed")
+
+// ErrPartial is returned together with the data ffprobe wrote before the probe timed out, see WithPartialOnTimeout,
+// or before ffprobe exited with an error, which it can do on truncated input after determining the format and streams.
+var ErrPartial = errors.New("partial ffprobe output")
+
+// ErrProgressWithoutReader is returned when WithProgress is used to probe a URL without a reader supplied with
+// WithStdin, as there is no reader to report progress for.
+var ErrProgressWithoutReader = errors.New("progress requires a reader; use WithStdin or ProbeReaderWithOptions")
+
 // SetFFProbeBinPath sets the global path to find and execute the ffprobe program
 func SetFFProbeBinPath(newBinPath string) {
 	binPath = newBinPath
 }
 
+// SetDefaultArgs sets global ffprobe parameters that are added to every probe, such as "-probesize" tuning. They are
+// placed after the parameters set by this package and before the per-call parameters, like extraFFProbeOptions and
+// WithExtraArgs. As ffprobe uses the last value when an option is given more than once, per-call parameters take
+// precedence over the defaults, which in turn take precedence over the parameters of this package. Calling it again
+// replaces the previous defaults, it is safe to call concurrently with running probes.
+func SetDefaultArgs(args []string) {
+	defaultArgsMu.Lock()
+	defaultArgs = append([]string(nil), args...)
+	defaultArgsMu.Unlock()
+}
+
+// getDefaultArgs returns the global default ffprobe parameters, the returned slice must not be modified
+func getDefaultArgs() []string {
+	defaultArgsMu.RLock()
+	defer defaultArgsMu.RUnlock()
+	return defaultArgs
+}
+
+// ExecError is returned when the ffprobe process could not be run or exited with an error.
+type ExecError struct {
+	// BinPath is the path of the ffprobe program that was executed
+	BinPath string
+	// Stderr is the output ffprobe wrote to stderr
+	Stderr string
+	// Err is the error returned when running the process
+	Err error
+}
+
+func newExecError(stderr string, err error) *ExecError {
+	return &ExecError{
+		BinPath: binPath,
+		Stderr:  stderr,
+		Err:     err,
+	}
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("error running %s [%s] %v", e.BinPath, e.Stderr, e.Err)
+}
+
+// Unwrap returns the error returned when running the process
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// StderrLines returns the non-empty lines ffprobe wrote to stderr, with surrounding whitespace removed.
+func (e *ExecError) StderrLines() []string {
+	var lines []string
+	for _, line := range strings.Split(e.Stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
 // ProbeURL is used to probe the given media file using ffprobe. The URL can be a local path, a HTTP URL or any other
 // protocol supported by ffprobe, see here for a full list: https://ffmpeg.org/ffmpeg-protocols.html
 // This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
 // Any additional ffprobe parameter can be supplied as well using extraFFProbeOptions.
 func ProbeURL(ctx context.Context, fileURL string, extraFFProbeOptions ...string) (data *ProbeData, err error) {
-	args := append([]string{
-		"-loglevel", "fatal",
-		"-print_format", "json",
-		"-show_format",
-		"-show_streams",
-		"-show_chapters",
-	}, extraFFProbeOptions...)
+	return ProbeURLWithOptions(ctx, fileURL, WithExtraArgs(extraFFProbeOptions...))
+}
 
-	// Add the file argument
-	args = append(args, fileURL)
+// ProbeURLWithOptions works like ProbeURL, but allows configuring the probe using options.
+func ProbeURLWithOptions(ctx context.Context, fileURL string, opts ...Option) (data *ProbeData, err error) {
+	options := newProbeOptions(opts)
+	if err = checkOutputFormat(options.defaultArgs, options.extraArgs); err != nil {
+		return nil, err
+	}
+	if err = checkInputScheme(fileURL, options.allowedSchemes); err != nil {
+		return nil, err
+	}
+	if options.progress != nil && options.stdin == nil {
+		return nil, ErrProgressWithoutReader
+	}
+	ctx, cancelFn := options.withDeadline(ctx)
+	defer cancelFn()
+	return probeURL(ctx, fileURL, options)
+}
+
+// probeURL probes the given media file with the already validated options, retrying failed stream detection when
+// enabled with WithAutoRetryDetection.
+func probeURL(ctx context.Context, fileURL string, options *probeOptions) (data *ProbeData, err error) {
+	data, err = probeURLOnce(ctx, fileURL, options)
+	if err != nil || !options.autoRetryDetection || options.stdin != nil || !detectionFailed(data) {
+		return data, err
+	}
+
+	retryOptions := options.withIncreasedDetection()
+	if retryOptions == nil {
+		return data, nil
+	}
+	retryData, retryErr := probeURLOnce(ctx, fileURL, retryOptions)
+	if retryErr != nil {
+		return data, nil
+	}
+	return retryData, nil
+}
+
+// detectionFailed returns whether ffprobe found no streams, or audio or video streams of which it could not
+// determine the codec parameters.
+func detectionFailed(data *ProbeData) bool {
+	if len(data.Streams) == 0 {
+		return true
+	}
+	for _, s := range data.Streams {
+		if s == nil {
+			continue
+		}
+		switch s.CodecType {
+		case string(StreamVideo):
+			if s.CodecName == "" || s.Width == 0 || s.Height == 0 {
+				return true
+			}
+		case string(StreamAudio):
+			if s.CodecName == "" || s.SampleRate == "" || s.SampleRate == "0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// probeURLOnce probes the given media file with the already validated options.
+func probeURLOnce(ctx context.Context, fileURL string, options *probeOptions) (data *ProbeData, err error) {
+	args := probeArgs(options, fileURL)
 
 	cmd := exec.CommandContext(ctx, binPath, args...)
+	if options.stdin != nil {
+		cmd.Stdin = newProgressReader(options.stdin, options.progress)
+	}
 	cmd.SysProcAttr = procAttributes()
 
-	return runProbe(cmd)
+	return runProbe(ctx, cmd, options)
+}
+
+// Duration is used to get the duration of the given media file using ffprobe. Only the duration fields and the stream
+// tags are shown by ffprobe, which keeps the output small, but ffprobe still analyses the streams like it does for a
+// full probe. When the container does not report a duration, the duration of the longest stream is returned, which
+// falls back to the Matroska "DURATION" tag. ErrFieldNotFound is returned when no duration is known at all.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+// Any additional ffprobe parameter can be supplied as well using extraFFProbeOptions.
+func Duration(ctx context.Context, fileURL string, extraFFProbeOptions ...string) (time.Duration, error) {
+	data, err := ProbeURLWithOptions(ctx, fileURL,
+		withSections(),
+		WithShowEntries(map[string][]string{
+			"format": {"duration"},
+			"stream": {"index", "duration"},
+			// All tags are shown, as the Matroska "DURATION" tag may have a language suffix like "DURATION-eng"
+			"stream_tags": nil,
+		}),
+		WithExtraArgs(extraFFProbeOptions...),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if data.Format.DurationSeconds > 0 {
+		return data.Format.Duration(), nil
+	}
+	if duration := data.LongestStreamDuration(); duration > 0 {
+		return duration, nil
+	}
+	return 0, fmt.Errorf("duration: %w", ErrFieldNotFound)
+}
+
+// ProbeFormat is used to probe only the container level information of the given media file, like the format name,
+// duration, size and tags. Only the format is shown, which keeps the output small for files with many streams. Note
+// that ffprobe still analyses the streams, as it needs them to determine the duration and bit rate of the format,
+// so this is not considerably faster than a full probe.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+func ProbeFormat(ctx context.Context, fileURL string, opts ...Option) (*Format, error) {
+	data, err := ProbeURLWithOptions(ctx, fileURL, append([]Option{withSections("-show_format")}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return data.Format, nil
 }
 
 // ProbeReader is used to probe a media file using an io.Reader. The reader is piped to the stdin of the ffprobe command
 // and the data is returned.
+// The reader is not used anymore once the probe returns, so it can be reused right away. The exception is a probe of
+// which the context is done, e.g. by a timeout: ffprobe is then killed and the probe returns without waiting for a
+// read from the reader that blocks, which may still return after the probe did.
 // This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
 // Any additional ffprobe parameter can be supplied as well using extraFFProbeOptions.
 func ProbeReader(ctx context.Context, reader io.Reader, extraFFProbeOptions ...string) (data *ProbeData, err error) {
+	return ProbeReaderWithOptions(ctx, reader, WithExtraArgs(extraFFProbeOptions...))
+}
+
+// ProbeReaderWithOptions works like ProbeReader, but allows configuring the probe using options.
+func ProbeReaderWithOptions(ctx context.Context, reader io.Reader, opts ...Option) (data *ProbeData, err error) {
+	options := newProbeOptions(opts)
+	if err = checkOutputFormat(options.defaultArgs, options.extraArgs); err != nil {
+		return nil, err
+	}
+	ctx, cancelFn := options.withDeadline(ctx)
+	defer cancelFn()
+
+	reader = newProgressReader(reader, options.progress)
+	switch options.readerStrategy {
+	case StrategyTempFile:
+		return probeTempFile(ctx, reader, options)
+	case StrategyAuto:
+		var needsSeeking bool
+		reader, needsSeeking, err = sniffSeeking(reader)
+		if err != nil {
+			return nil, err
+		}
+		if needsSeeking {
+			return probeTempFile(ctx, reader, options)
+		}
+	}
+
+	if options.pipeFD > 2 {
+		return probePipeFD(ctx, reader, options)
+	}
+
+	// Use the file from stdin
+	args := probeArgs(options, "-")
+
+	cmd := exec.CommandContext(ctx, binPath, args...)
+	cmd.Stdin = reader
+	cmd.SysProcAttr = procAttributes()
+
+	return runProbe(ctx, cmd, options)
+}
+
+// probePipeFD probes the reader by passing it to ffprobe on the file descriptor configured with WithPipeFD.
+func probePipeFD(ctx context.Context, reader io.Reader, options *probeOptions) (data *ProbeData, err error) {
+	pipeReader, pipeWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating pipe: %w", err)
+	}
+
+	// Use the file from the pipe file descriptor
+	args := probeArgs(options, fmt.Sprintf("pipe:%d", options.pipeFD))
+
+	cmd := exec.CommandContext(ctx, binPath, args...)
+	cmd.ExtraFiles = make([]*os.File, options.pipeFD-2)
+	cmd.ExtraFiles[options.pipeFD-3] = pipeReader
+	cmd.SysProcAttr = procAttributes()
+
+	copyDone := make(chan struct{})
+	go func() {
+		defer close(copyDone)
+		copyStdin(pipeWriter, reader)
+	}()
+
+	data, err = runProbe(ctx, cmd, options)
+
+	// Closing our end of the pipe makes the copy fail if ffprobe exited before reading everything
+	_ = pipeReader.Close()
+	waitForCopy(ctx, copyDone)
+
+	return data, err
+}
+
+// ProbeRawPCM is used to probe headerless PCM audio from an io.Reader. As raw PCM carries no information about its
+// layout, the sample format (e.g. "s16le" or "f32be"), the sample rate and the channel count have to be supplied.
+// The channel count is passed as an unordered channel layout, which requires ffprobe 5.1 or newer.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+func ProbeRawPCM(ctx context.Context, reader io.Reader, sampleFmt string, sampleRate, channels int, opts ...Option) (data *ProbeData, err error) {
+	// Copy the options, so the caller's slice is never written to
+	opts = append(append([]Option(nil), opts...), WithInputArgs(pcmInputArgs(sampleFmt, sampleRate, channels)...))
+	return ProbeReaderWithOptions(ctx, reader, opts...)
+}
+
+// pcmInputArgs returns the input options for the raw PCM demuxer of the given sample format
+func pcmInputArgs(sampleFmt string, sampleRate, channels int) []string {
+	return []string{
+		"-f", sampleFmt,
+		"-sample_rate", strconv.Itoa(sampleRate),
+		"-ch_layout", fmt.Sprintf("%dC", channels),
+	}
+}
+
+// ImageSequence holds the result of ProbeImageSequence
+type ImageSequence struct {
+	// Frames is the number of images in the sequence
+	Frames int64
+	// Width and Height are the dimensions of the images
+	Width, Height int
+	// Data is the full probe data of the sequence
+	Data *ProbeData
+}
+
+// ProbeImageSequence is used to probe a sequence of images matching the pattern, e.g. "frame_%04d.png", as a video
+// with the given frame rate. The images are counted by reading all of them, which is more reliable than the frame
+// count the image2 demuxer reports. ErrFieldNotFound is returned when the sequence has no video stream.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+func ProbeImageSequence(ctx context.Context, pattern string, frameRate float64, opts ...Option) (*ImageSequence, error) {
+	// Copy the options, so the caller's slice is never written to
+	opts = append(append([]Option(nil), opts...), WithCountPackets(), WithInputArgs(imageSequenceInputArgs(frameRate)...))
+	data, err := ProbeURLWithOptions(ctx, pattern, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := data.FirstVideoStream()
+	if stream == nil {
+		return nil, fmt.Errorf("video stream: %w", ErrFieldNotFound)
+	}
+	frames, err := strconv.ParseInt(stream.NbReadPackets, 10, 64)
+	if err != nil {
+		frames, err = stream.NumberOfFrames()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &ImageSequence{
+		Frames: frames,
+		Width:  stream.Width,
+		Height: stream.Height,
+		Data:   data,
+	}, nil
+}
+
+// imageSequenceInputArgs returns the input options for the image2 demuxer at the given frame rate
+func imageSequenceInputArgs(frameRate float64) []string {
+	return []string{
+		"-f", "image2",
+		"-framerate", strconv.FormatFloat(frameRate, 'f', -1, 64),
+	}
+}
+
+// ProbeReaderAt is used to probe a media file of the given size using an io.ReaderAt. Unlike ProbeReader this allows
+// ffprobe to seek in the file, which is needed for some formats, like MP4 files with the moov atom at the end.
+// When the reader is an *os.File, the file is probed by its path. Otherwise the content is copied to a temporary
+// file first, which is removed afterwards, and the filename of the format is reported as "pipe:".
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+func ProbeReaderAt(ctx context.Context, reader io.ReaderAt, size int64, opts ...Option) (data *ProbeData, err error) {
+	options := newProbeOptions(opts)
+	if err = checkOutputFormat(options.defaultArgs, options.extraArgs); err != nil {
+		return nil, err
+	}
+	ctx, cancelFn := options.withDeadline(ctx)
+	defer cancelFn()
+
+	if file, ok := reader.(*os.File); ok {
+		return probeURL(ctx, file.Name(), options)
+	}
+	return probeTempFile(ctx, io.NewSectionReader(reader, 0, size), options)
+}
+
+// ProbeOpenFile is used to probe an already opened file by its path, so ffprobe can seek in it, unlike when passing
+// the file to ProbeReader. The file must still exist at the path it was opened with. The file is not read from or
+// closed, so it can be used for other purposes afterwards.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+func ProbeOpenFile(ctx context.Context, file *os.File, opts ...Option) (data *ProbeData, err error) {
+	return ProbeReaderAt(ctx, file, 0, opts...)
+}
+
+// probeTempFile copies the reader to a temporary file and probes that file. The filename of the format is reported as
+// "pipe:", like for a probe of stdin, as the temporary file no longer exists once the probe returns.
+func probeTempFile(ctx context.Context, reader io.Reader, options *probeOptions) (data *ProbeData, err error) {
+	tempFile, err := ioutil.TempFile("", "go-ffprobe-*")
+	if err != nil {
+		return nil, fmt.Errorf("error creating temporary file: %w", err)
+	}
+	defer func() {
+		_ = os.Remove(tempFile.Name())
+	}()
+
+	_, err = io.Copy(tempFile, reader)
+	closeErr := tempFile.Close()
+	if err != nil {
+		return nil, fmt.Errorf("error writing temporary file: %w", err)
+	}
+	if closeErr != nil {
+		return nil, fmt.Errorf("error closing temporary file: %w", closeErr)
+	}
+
+	data, err = probeURL(ctx, tempFile.Name(), options)
+	if data != nil && data.Format != nil {
+		data.Format.Filename = "pipe:"
+	}
+	return data, err
+}
+
+// sniffSeeking reads the start of the reader to determine whether ffprobe likely needs to seek in it. This is the case
+// for ISO BMFF files (MP4, MOV, M4A, 3GP), as their moov atom with the stream information can be at the end of the file.
+// The returned reader still contains the complete content.
+func sniffSeeking(reader io.Reader) (io.Reader, bool, error) {
+	header := make([]byte, 8)
+	n, err := io.ReadFull(reader, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, false, fmt.Errorf("error reading header: %w", err)
+	}
+	header = header[:n]
+	reader = io.MultiReader(bytes.NewReader(header), reader)
+
+	if n < 8 {
+		return reader, false, nil
+	}
+	switch string(header[4:8]) {
+	case "ftyp", "moov", "mdat", "free", "wide", "skip":
+		return reader, true, nil
+	}
+	return reader, false, nil
+}
+
+// ProbeRaw is used to probe the given media file using ffprobe, returning the output of ffprobe verbatim in the
+// requested output format (json, csv, flat, xml, etc) without parsing it.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+// Any additional ffprobe parameter can be supplied as well using extraFFProbeOptions.
+func ProbeRaw(ctx context.Context, fileURL, outputFormat string, extraFFProbeOptions ...string) ([]byte, error) {
+	defaultArgs := getDefaultArgs()
+	for _, arg := range append(append([]string(nil), defaultArgs...), extraFFProbeOptions...) {
+		if isOutputFormatFlag(arg) {
+			return nil, fmt.Errorf("%w (got %s)", ErrOutputFormatArg, arg)
+		}
+	}
+
 	args := append([]string{
 		"-loglevel", "fatal",
-		"-print_format", "json",
+		"-print_format", outputFormat,
 		"-show_format",
 		"-show_streams",
 		"-show_chapters",
-	}, extraFFProbeOptions...)
+	}, defaultArgs...)
+	args = append(args, extraFFProbeOptions...)
 
-	// Add the file from stdin argument
-	args = append(args, "-")
+	// Add the file argument
+	args = append(args, fileURL)
 
 	cmd := exec.CommandContext(ctx, binPath, args...)
-	cmd.Stdin = reader
 	cmd.SysProcAttr = procAttributes()
 
-	return runProbe(cmd)
+	var outputBuf bytes.Buffer
+	var stdErr bytes.Buffer
+
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = &stdErr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, newExecError(stdErr.String(), err)
+	}
+	return outputBuf.Bytes(), nil
+}
+
+// probeArgs returns the ffprobe arguments for the given options and input file.
+func probeArgs(options *probeOptions, input string) []string {
+	args := []string{
+		"-loglevel", "fatal",
+		// Compact JSON puts every section on a single line, which is less output to transfer and parse
+		"-print_format", "json=c=1",
+	}
+	args = append(args, options.sections...)
+	if options.countPackets {
+		args = append(args, "-count_packets")
+	}
+	if options.showLog {
+		args = append(args, "-show_frames", "-show_log", strconv.Itoa(options.logLevel))
+	}
+	if options.noPrivateData {
+		// show_private_data is a boolean option, which takes no value and is disabled with the "no" prefix
+		args = append(args, "-noshow_private_data")
+	}
+	if options.optionalFields != "" {
+		args = append(args, "-show_optional_fields", options.optionalFields)
+	}
+	if options.dataHash != "" {
+		args = append(args, "-show_data_hash", options.dataHash)
+	}
+	if options.showEntries != "" {
+		args = append(args, "-show_entries", options.showEntries)
+	}
+	if options.readIntervals != "" {
+		args = append(args, "-read_intervals", options.readIntervals)
+	}
+	args = append(args, options.defaultArgs...)
+	args = append(args, options.extraArgs...)
+
+	// Add the input options and the file argument
+	args = append(args, options.inputArgs...)
+	if options.keyframesOnly && showsFrames(args) {
+		args = append(args, "-skip_frame", "nokey")
+	}
+	return append(args, input)
+}
+
+// showsFrames returns whether the arguments make ffprobe decode and report the frames.
+func showsFrames(args []string) bool {
+	for _, arg := range args {
+		if arg == "-show_frames" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOutputFormat returns an error when any of the given argument lists select an output format other than JSON.
+func checkOutputFormat(argLists ...[]string) error {
+	for _, args := range argLists {
+		for i := 0; i < len(args)-1; i++ {
+			if !isOutputFormatFlag(args[i]) {
+				continue
+			}
+			format := args[i+1]
+			if format != "json" && !strings.HasPrefix(format, "json=") {
+				return fmt.Errorf("%w (got %s %s)", ErrNonJSONOutputFormat, args[i], format)
+			}
+		}
+	}
+	return nil
+}
+
+// checkInputScheme returns an error when allowed is not empty and the scheme of the input is not in it.
+func checkInputScheme(input string, allowed []string) error {
+	if len(allowed) == 0 {
+		return nil
+	}
+	scheme := inputScheme(input)
+	for _, a := range allowed {
+		if strings.EqualFold(a, scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w (got %s)", ErrInputSchemeNotAllowed, scheme)
+}
+
+// inputScheme returns the lower case name of the protocol ffprobe uses to open the input. Like ffmpeg, it takes the
+// name before the first colon or comma, so "concat:a.mp4|b.mp4" uses the concat protocol. Inputs without protocol
+// name, including Windows paths like "C:\video.mp4", use the file protocol.
+func inputScheme(input string) string {
+	n := strings.IndexFunc(input, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.')
+	})
+	if n <= 1 || (input[n] != ':' && input[n] != ',') {
+		return "file"
+	}
+	return strings.ToLower(input[:n])
+}
+
+// isOutputFormatFlag returns whether the argument is one of the ffprobe options selecting the output format
+func isOutputFormatFlag(arg string) bool {
+	return arg == "-of" || arg == "-print_format" || arg == "-output_format"
 }
 
 // runProbe takes the fully configured ffprobe command and executes it, returning the ffprobe data if everything went fine.
-func runProbe(cmd *exec.Cmd) (data *ProbeData, err error) {
-	var outputBuf bytes.Buffer
+func runProbe(ctx context.Context, cmd *exec.Cmd, options *probeOptions) (data *ProbeData, err error) {
+	if options.logger != nil {
+		defer func(start time.Time) {
+			options.logger(ctx, cmd.Args[1:], time.Since(start), err)
+		}(time.Now())
+	}
+
+	outputBuf := limitedBuffer{
+		limit: options.maxOutputBytes,
+		onExceed: func() {
+			_ = cmd.Process.Kill()
+		},
+	}
+	outputBuf.grow(options.outputBufferHint)
 	var stdErr bytes.Buffer
 
 	cmd.Stdout = &outputBuf
 	cmd.Stderr = &stdErr
 
-	err = cmd.Run()
-	if err != nil {
-		return nil, fmt.Errorf("error running %s [%s] %w", binPath, stdErr.String(), err)
+	var endTrace func(err error)
+	if options.tracer != nil {
+		endTrace = options.tracer(ctx, cmd.Args[1:])
+	}
+	start := time.Now()
+	runErr := runCommand(ctx, cmd, options)
+	if endTrace != nil {
+		endTrace(runErr)
+	}
+	if options.elapsed != nil {
+		*options.elapsed = time.Since(start)
+	}
+	if outputBuf.exceeded {
+		return nil, fmt.Errorf("%w of %d bytes", ErrOutputTooLarge, options.maxOutputBytes)
 	}
 
 	data = &ProbeData{}
-	err = json.Unmarshal(outputBuf.Bytes(), data)
+	err = decodeOutput(outputBuf.Bytes(), data)
+	var partialErr error
+	if runErr != nil {
+		var exitErr *exec.ExitError
+		switch {
+		case options.partialOnTimeout && err == nil && errors.Is(ctx.Err(), context.DeadlineExceeded):
+			// The output was complete before the process was killed, return it marked as partial
+			partialErr = fmt.Errorf("%w: %v", ErrPartial, ctx.Err())
+		case err == nil && data.Format != nil && len(data.Streams) > 0 && errors.As(runErr, &exitErr) && ctx.Err() == nil:
+			// On truncated input ffprobe may still have determined the format and streams before exiting with an
+			// error, return that data marked as partial in that case. A killed process is never tolerated.
+			partialErr = fmt.Errorf("%w: %v", ErrPartial, newExecError(stdErr.String(), runErr))
+		default:
+			return nil, newExecError(stdErr.String(), runErr)
+		}
+	}
 	if err != nil {
 		return data, fmt.Errorf("error parsing ffprobe output: %w", err)
 	}
 
-	if data.Format == nil {
+	if data.Format == nil && partialErr == nil {
 		return data, fmt.Errorf("no format data found in ffprobe output")
 	}
 
 	// Populate the old Tags structs for backwards compatibility purposes:
-	if len(data.Format.TagList) > 0 {
+	if data.Format != nil && len(data.Format.TagList) > 0 {
 		data.Format.Tags = &FormatTags{}
 		data.Format.Tags.setFrom(data.Format.TagList)
 	}
@@ -93,5 +667,186 @@ func runProbe(cmd *exec.Cmd) (data *ProbeData, err error) {
 		str.Tags.setFrom(str.TagList)
 	}
 
-	return data, nil
+	if options.showLog {
+		err = setStreamLogs(data, outputBuf.Bytes())
+		if err != nil {
+			return data, fmt.Errorf("error parsing ffprobe frame logs: %w", err)
+		}
+	}
+
+	return data, partialErr
+}
+
+// decodeOutput decodes the JSON object in the ffprobe output into v. Anything before the start of the object and after
+// its end is ignored, as some ffprobe builds or wrapper scripts print stray lines to stdout.
+func decodeOutput(output []byte, v interface{}) error {
+	if start := bytes.IndexByte(output, '{'); start > 0 {
+		output = output[start:]
+	}
+	return json.NewDecoder(bytes.NewReader(output)).Decode(v)
+}
+
+// setStreamLogs collects the logs of all frames in the ffprobe output into the streams they belong to.
+func setStreamLogs(data *ProbeData, output []byte) error {
+	var frameData struct {
+		Frames []struct {
+			StreamIndex int        `json:"stream_index"`
+			Logs        []LogEntry `json:"logs"`
+		} `json:"frames"`
+	}
+	err := decodeOutput(output, &frameData)
+	if err != nil {
+		return err
+	}
+
+	for _, frame := range frameData.Frames {
+		for _, str := range data.Streams {
+			if str != nil && str.Index == frame.StreamIndex {
+				str.Logs = append(str.Logs, frame.Logs...)
+			}
+		}
+	}
+	return nil
+}
+
+// runCommand starts the command and waits for it to finish, applying the process related options.
+func runCommand(ctx context.Context, cmd *exec.Cmd, options *probeOptions) error {
+	if options.niceness != 0 && !nicenessSupported {
+		return ErrNicenessUnsupported
+	}
+
+	// When exec copies stdin itself, Wait blocks until the reader returns, even after the process was killed.
+	// Copy it ourselves instead, so a blocked reader cannot keep a killed probe from returning.
+	var stdin io.Reader
+	var stdinPipe io.WriteCloser
+	if _, isFile := cmd.Stdin.(*os.File); cmd.Stdin != nil && !isFile {
+		stdin = cmd.Stdin
+		cmd.Stdin = nil
+
+		var err error
+		stdinPipe, err = cmd.StdinPipe()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := cmd.Start()
+	if err != nil {
+		return err
+	}
+
+	if stdinPipe != nil {
+		copyDone := make(chan struct{})
+		go func() {
+			defer close(copyDone)
+			copyStdin(stdinPipe, stdin)
+		}()
+		// Wait closes the pipe, which ends the copy with its next write
+		defer waitForCopy(ctx, copyDone)
+	}
+
+	if options.niceness != 0 {
+		err = setNiceness(cmd.Process.Pid, options.niceness)
+		if err != nil {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+			return fmt.Errorf("error setting niceness: %w", err)
+		}
+	}
+
+	return cmd.Wait()
+}
+
+// copyStdin copies stdin to ffprobe, or the reader to the pipe of WithPipeFD, until the reader is exhausted or writing
+// fails. ffprobe stops reading once it has enough data, after which a write fails with EPIPE, or with os.ErrClosed
+// once the pipe was closed after ffprobe exited. These errors are expected and end the copy.
+func copyStdin(stdinPipe io.WriteCloser, stdin io.Reader) {
+	defer stdinPipe.Close()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := stdin.Read(buf)
+		if n > 0 {
+			if _, writeErr := stdinPipe.Write(buf[:n]); writeErr != nil {
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// waitForCopy waits until the copy of the reader to ffprobe ended, so the reader is not used after the probe returns.
+// When the context is done, ffprobe was killed and the copy is abandoned instead, as it may be blocked reading.
+func waitForCopy(ctx context.Context, copyDone <-chan struct{}) {
+	select {
+	case <-copyDone:
+		return
+	default:
+	}
+	select {
+	case <-copyDone:
+	case <-ctx.Done():
+	}
+}
+
+// progressReader reports the total number of bytes read to the progress function after every read
+type progressReader struct {
+	reader    io.Reader
+	processed int64
+	progress  func(processed int64)
+}
+
+// newProgressReader wraps the reader to report progress, the reader is returned as is without progress function.
+func newProgressReader(reader io.Reader, progress func(processed int64)) io.Reader {
+	if progress == nil {
+		return reader
+	}
+	return &progressReader{reader: reader, progress: progress}
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.processed += int64(n)
+		r.progress(r.processed)
+	}
+	return n, err
+}
+
+// limitedBuffer is a buffer that stops accepting data once the limit is exceeded. The onExceed function is
+// called once when that happens, after which all data is discarded.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+	onExceed func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.exceeded {
+		return len(p), nil
+	}
+	if b.limit > 0 && int64(b.buf.Len()+len(p)) > b.limit {
+		b.exceeded = true
+		b.onExceed()
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+// grow pre-allocates room for size bytes, at most the limit of the buffer
+func (b *limitedBuffer) grow(size int) {
+	if b.limit > 0 && int64(size) > b.limit {
+		size = int(b.limit)
+	}
+	if size > 0 {
+		b.buf.Grow(size)
+	}
+}
+
+// Bytes returns the data written to the buffer
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
 }
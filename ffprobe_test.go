@@ -1,18 +1,25 @@
 package ffprobe
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 )
 
 const (
-	testPath      = "assets/test.mp4"
-	testPathError = "assets/test.avi"
+	testPath          = "assets/test.mp4"
+	testPathFastStart = "assets/test_faststart.mp4"
+	testPathError     = "assets/test.avi"
 )
 
 func Test_ProbeURL(t *testing.T) {
@@ -27,6 +34,364 @@ func Test_ProbeURL(t *testing.T) {
 	validateData(t, data)
 }
 
+func Test_ProbeURLWithOptions_Timing(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	var elapsed time.Duration
+	data, err := ProbeURLWithOptions(ctx, testPath, WithTiming(&elapsed))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+
+	validateData(t, data)
+
+	if elapsed <= 0 {
+		t.Errorf("Expected probe duration to be measured, got %v", elapsed)
+	}
+}
+
+func Test_ProbeURLWithOptions_Logger(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	calls := 0
+	var loggedArgs []string
+	var loggedErr error
+	logger := func(ctx context.Context, args []string, elapsed time.Duration, err error) {
+		calls++
+		loggedArgs, loggedErr = args, err
+	}
+
+	_, err := ProbeURLWithOptions(ctx, testPath, WithLogger(logger))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+	if calls != 1 || loggedErr != nil || len(loggedArgs) == 0 || loggedArgs[len(loggedArgs)-1] != testPath {
+		t.Errorf("Unexpected logger calls %d with args %v and error %v", calls, loggedArgs, loggedErr)
+	}
+
+	_, err = ProbeURLWithOptions(ctx, testPath, WithLogger(logger), WithMaxOutputBytes(64))
+	if calls != 2 || loggedErr != err {
+		t.Errorf("Expected the error %v to be logged, got %v", err, loggedErr)
+	}
+}
+
+func Test_ProbeURLWithOptions_Tracer(t *testing.T) {
+	type ctxKey struct{}
+	ctx, cancelFn := context.WithTimeout(context.WithValue(context.Background(), ctxKey{}, "trace"), 3*time.Second)
+	defer cancelFn()
+
+	started, ended := false, false
+	tracer := func(ctx context.Context, args []string) func(err error) {
+		started = ctx.Value(ctxKey{}) == "trace" && len(args) > 0
+		return func(err error) {
+			ended = err == nil
+		}
+	}
+
+	data, err := ProbeURLWithOptions(ctx, testPath, WithTracer(tracer))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+	validateData(t, data)
+
+	if !started || !ended {
+		t.Errorf("Expected the trace to be started with the probe context and ended, got %v and %v", started, ended)
+	}
+}
+
+func Test_inputScheme(t *testing.T) {
+	tests := map[string]string{
+		"assets/test.mp4":                  "file",
+		"/tmp/test.mp4":                    "file",
+		`C:\videos\test.mp4`:               "file",
+		"file:///tmp/test.mp4":             "file",
+		"HTTPS://example.com/test.mp4":     "https",
+		"concat:a.mp4|b.mp4":               "concat",
+		"subfile,,start,0,end,100,,:a.mp4": "subfile",
+		"rtmp://example.com/live":          "rtmp",
+	}
+	for input, expected := range tests {
+		if scheme := inputScheme(input); scheme != expected {
+			t.Errorf("Expected scheme %s for %s, got %s", expected, input, scheme)
+		}
+	}
+}
+
+func Test_ProbeURLWithOptions_AllowedInputSchemes(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	for _, input := range []string{testPath, "file://" + testPath, "concat:" + testPath + "|" + testPath} {
+		_, err := ProbeURLWithOptions(ctx, input, WithAllowedInputSchemes("http", "https"))
+		if !errors.Is(err, ErrInputSchemeNotAllowed) {
+			t.Errorf("Expected ErrInputSchemeNotAllowed for %s, got %v", input, err)
+		}
+	}
+
+	data, err := ProbeURLWithOptions(ctx, testPath, WithAllowedInputSchemes("https", "FILE"))
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	validateData(t, data)
+}
+
+func Test_ProbeURLWithOptions_MaxOutputBytes(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	_, err := ProbeURLWithOptions(ctx, testPath, WithMaxOutputBytes(64))
+	if !errors.Is(err, ErrOutputTooLarge) {
+		t.Errorf("Expected ErrOutputTooLarge, got %v", err)
+	}
+}
+
+func Test_ProbeURLWithOptions_Niceness(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	data, err := ProbeURLWithOptions(ctx, testPath, WithNiceness(10))
+	if runtime.GOOS == "windows" {
+		if !errors.Is(err, ErrNicenessUnsupported) {
+			t.Errorf("Expected ErrNicenessUnsupported on Windows, got %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+
+	validateData(t, data)
+}
+
+func Test_Duration(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	duration, err := Duration(ctx, testPath)
+	if err != nil {
+		t.Fatalf("Error getting duration: %v", err)
+	}
+	if duration.Seconds() != 5.312 {
+		t.Errorf("this video is 5.312s, got %v", duration)
+	}
+}
+
+func Test_Duration_StreamTags(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a shell script")
+	}
+
+	// A fake ffprobe that reports a Matroska file of which only the streams have a duration tag
+	defer setFakeFFProbe(t, `case "$*" in
+*stream_tags*) echo '{"streams":[{"index":0,"tags":{"DURATION-eng":"00:01:30.500000000"}}],"format":{}}' ;;
+*) echo '{"streams":[{"index":0}],"format":{}}' ;;
+esac`)()
+
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	duration, err := Duration(ctx, "test.mkv")
+	if err != nil {
+		t.Fatalf("Error getting duration: %v", err)
+	}
+	if duration != 90500*time.Millisecond {
+		t.Errorf("Expected the duration from the stream tag, got %v", duration)
+	}
+}
+
+func Test_ProbeFormat(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	format, err := ProbeFormat(ctx, testPath)
+	if err != nil {
+		t.Fatalf("Error getting format: %v", err)
+	}
+	if format.FormatName != "mov,mp4,m4a,3gp,3g2,mj2" {
+		t.Errorf("Unexpected format name: %s", format.FormatName)
+	}
+	if format.DurationSeconds != 5.312 {
+		t.Errorf("this video is 5.312s, got %v", format.DurationSeconds)
+	}
+}
+
+func Test_CompareMedia(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	diff, err := CompareMedia(ctx, testPath, testPath)
+	if err != nil {
+		t.Fatalf("Error comparing media: %v", err)
+	}
+	if !diff.Preserved(0) || len(diff.CodecChanges) > 0 {
+		t.Errorf("Expected no differences comparing a file to itself, got %+v", diff)
+	}
+
+	_, err = CompareMedia(ctx, testPath, testPathError)
+	if err == nil {
+		t.Errorf("Expected an error comparing to an invalid file")
+	}
+}
+
+func Test_ProbeURLWithOptions_ConcatDemuxer(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	testFile, err := filepath.Abs(testPath)
+	if err != nil {
+		t.Fatalf("Error getting test file path: %v", err)
+	}
+	listFile, err := ioutil.TempFile("", "go-ffprobe-concat-*.txt")
+	if err != nil {
+		t.Fatalf("Error creating list file: %v", err)
+	}
+	defer os.Remove(listFile.Name())
+	_, err = fmt.Fprintf(listFile, "file '%s'\nfile '%s'\n", testFile, testFile)
+	if err != nil {
+		t.Fatalf("Error writing list file: %v", err)
+	}
+	_ = listFile.Close()
+
+	data, err := ProbeURLWithOptions(ctx, listFile.Name(), WithConcatDemuxer())
+	if err != nil {
+		t.Fatalf("Error getting data: %v", err)
+	}
+	if data.Format.Duration() < 10*time.Second {
+		t.Errorf("Expected the duration of both segments, got %v", data.Format.Duration())
+	}
+}
+
+func Test_ProbeReaderWithOptions_Timeout(t *testing.T) {
+	// A reader that never returns data keeps ffprobe waiting until the timeout kills it
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	start := time.Now()
+	_, err := ProbeReaderWithOptions(context.Background(), reader, WithTimeout(500*time.Millisecond))
+	if err == nil {
+		t.Errorf("Expected error when the probe times out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the probe to be killed on timeout, took %v", elapsed)
+	}
+}
+
+// slowReader returns the content followed by an endless stream of zeroes, sleeping before every read
+type slowReader struct {
+	content []byte
+	delay   time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(r.delay)
+	if len(r.content) > 0 {
+		n := copy(p, r.content)
+		r.content = r.content[n:]
+		return n, nil
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// returnReader fails the test when a read from it ends after returned is set, which the race detector reports as well,
+// as returned is not synchronized on purpose.
+type returnReader struct {
+	t        *testing.T
+	reader   io.Reader
+	returned bool
+}
+
+func (r *returnReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if r.returned {
+		r.t.Errorf("Expected the reader not to be read after the probe returned")
+	}
+	return n, err
+}
+
+func Test_ProbeReaderWithOptions_NoReadAfterReturn(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a shell script")
+	}
+
+	// A fake ffprobe that exits once it has read the first bytes, while the next read from the slow reader is running
+	defer setFakeFFProbe(t, `case "$*" in
+*pipe:3*) head -c 1000 <&3 >/dev/null ;;
+*) head -c 1000 >/dev/null ;;
+esac
+echo '{"streams":[{"index":0,"codec_type":"video","codec_name":"h264"}],"format":{"format_name":"mp4"}}'`)()
+
+	for name, opts := range map[string][]Option{"stdin": nil, "pipe fd": {WithPipeFD(3)}} {
+		ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+
+		reader := &returnReader{t: t, reader: &slowReader{delay: 20 * time.Millisecond}}
+		_, err := ProbeReaderWithOptions(ctx, reader, opts...)
+		reader.returned = true
+		cancelFn()
+		if err != nil {
+			t.Errorf("Error getting data with %s: %v", name, err)
+		}
+
+		// Give a copy that is still running the chance to read
+		time.Sleep(50 * time.Millisecond)
+		if copyStdinRunning() {
+			t.Errorf("Expected the copy to end with the %s probe", name)
+		}
+	}
+}
+
+// copyStdinRunning returns whether any goroutine is still copying a reader to ffprobe
+func copyStdinRunning() bool {
+	buf := make([]byte, 1<<20)
+	n := runtime.Stack(buf, true)
+	return bytes.Contains(buf[:n], []byte(".copyStdin("))
+}
+
+// setFakeFFProbe makes the probes execute a shell script with the given body instead of ffprobe, until the returned
+// function is called.
+func setFakeFFProbe(t *testing.T, body string) func() {
+	dir, err := ioutil.TempDir("", "go-ffprobe-fake")
+	if err != nil {
+		t.Fatalf("Error creating temp dir: %v", err)
+	}
+	script := filepath.Join(dir, "ffprobe")
+	err = ioutil.WriteFile(script, []byte("#!/bin/sh\n"+body+"\n"), 0o700)
+	if err != nil {
+		t.Fatalf("Error writing script: %v", err)
+	}
+	SetFFProbeBinPath(script)
+	return func() {
+		SetFFProbeBinPath("ffprobe")
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func Test_ProbeURLWithOptions_PartialOnTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a shell script")
+	}
+
+	// A fake ffprobe that writes its output and then hangs
+	defer setFakeFFProbe(t, "echo '{\"format\":{\"format_name\":\"mp4\"}}'\nexec sleep 10")()
+
+	data, err := ProbeURLWithOptions(context.Background(), testPath, WithTimeout(500*time.Millisecond), WithPartialOnTimeout())
+	if !errors.Is(err, ErrPartial) {
+		t.Fatalf("Expected ErrPartial, got %v", err)
+	}
+	if data == nil || data.Format == nil || data.Format.FormatName != "mp4" {
+		t.Errorf("Expected the partial data to be returned, got %+v", data)
+	}
+
+	_, err = ProbeURLWithOptions(context.Background(), testPath, WithTimeout(500*time.Millisecond))
+	if err == nil || errors.Is(err, ErrPartial) {
+		t.Errorf("Expected the process error without WithPartialOnTimeout, got %v", err)
+	}
+}
+
 func Test_ProbeURL_Error(t *testing.T) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancelFn()
@@ -39,6 +404,14 @@ func Test_ProbeURL_Error(t *testing.T) {
 	if strings.Contains(err.Error(), "[]") {
 		t.Errorf("No stderr included in error message")
 	}
+
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Fatalf("Expected an ExecError, got %T", err)
+	}
+	if len(execErr.StderrLines()) == 0 {
+		t.Errorf("No stderr lines included in error")
+	}
 }
 
 func Test_ProbeURL_HTTP(t *testing.T) {
@@ -74,6 +447,61 @@ func Test_ProbeURL_HTTP(t *testing.T) {
 	}
 }
 
+func Test_Versions(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	report, err := Versions(ctx)
+	if err != nil {
+		t.Fatalf("Error getting versions: %v", err)
+	}
+	if report.ProgramVersion.Version == "" {
+		t.Errorf("Expected the ffprobe version to be reported")
+	}
+	if report.Libraries()["libavformat"] == "" {
+		t.Errorf("Expected the libavformat version to be reported, got %v", report.Libraries())
+	}
+}
+
+func Test_ProbeURL_NonJSONOutputFormat(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	_, err := ProbeURL(ctx, testPath, "-of", "xml")
+	if !errors.Is(err, ErrNonJSONOutputFormat) {
+		t.Errorf("Expected ErrNonJSONOutputFormat, got %v", err)
+	}
+}
+
+func Test_ProbeRaw(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	output, err := ProbeRaw(ctx, testPath, "flat")
+	if err != nil {
+		t.Fatalf("Error getting raw data: %v", err)
+	}
+
+	if !strings.Contains(string(output), "format.tags.major_brand=") {
+		t.Errorf("Expected flat output containing the major brand, got: %s", output)
+	}
+}
+
+func Test_ProbeRaw_OutputFormatArgs(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	if _, err := ProbeRaw(ctx, testPath, "flat", "-of", "csv"); !errors.Is(err, ErrOutputFormatArg) {
+		t.Errorf("Expected ErrOutputFormatArg for extra args, got %v", err)
+	}
+
+	SetDefaultArgs([]string{"-print_format", "xml"})
+	defer SetDefaultArgs(nil)
+	if _, err := ProbeRaw(ctx, testPath, "flat"); !errors.Is(err, ErrOutputFormatArg) {
+		t.Errorf("Expected ErrOutputFormatArg for default args, got %v", err)
+	}
+}
+
 func Test_ProbeReader(t *testing.T) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancelFn()
@@ -91,6 +519,206 @@ func Test_ProbeReader(t *testing.T) {
 	validateData(t, data)
 }
 
+func Test_ProbeReaderWithOptions_PipeFD(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	fileReader, err := os.Open(testPath)
+	if err != nil {
+		t.Errorf("Error opening test file: %v", err)
+	}
+	defer fileReader.Close()
+
+	data, err := ProbeReaderWithOptions(ctx, fileReader, WithPipeFD(3))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+
+	validateData(t, data)
+}
+
+func Test_ProbeReaderWithOptions_PipeFDBlockingReader(t *testing.T) {
+	// A reader that never returns data must not keep the probe from returning once ffprobe was killed
+	reader, writer := io.Pipe()
+	defer writer.Close()
+
+	start := time.Now()
+	_, err := ProbeReaderWithOptions(context.Background(), reader, WithPipeFD(3), WithTimeout(500*time.Millisecond))
+	if err == nil {
+		t.Errorf("Expected error when the probe times out")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the probe to return on timeout, took %v", elapsed)
+	}
+}
+
+func Test_ProbeReaderAt(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	content, err := ioutil.ReadFile(testPath)
+	if err != nil {
+		t.Fatalf("Error reading test file: %v", err)
+	}
+
+	// A bytes.Reader is not a file, so it is probed using a temporary file
+	data, err := ProbeReaderAt(ctx, bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+
+	validateStreams(t, data)
+	if data.Format.Filename != "pipe:" {
+		t.Errorf("Expected the pipe: filename instead of the temporary file, got %s", data.Format.Filename)
+	}
+}
+
+func Test_ProbeOpenFile(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	file, err := os.Open(testPath)
+	if err != nil {
+		t.Fatalf("Error opening test file: %v", err)
+	}
+	defer file.Close()
+
+	data, err := ProbeOpenFile(ctx, file)
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+	validateData(t, data)
+
+	// The file must remain open and unread
+	header := make([]byte, 8)
+	if _, err = io.ReadFull(file, header); err != nil || string(header[4:]) != "ftyp" {
+		t.Errorf("Expected the file to remain usable, got %q (%v)", header, err)
+	}
+}
+
+func Test_ProbeURLWithOptions_Stdin(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	fileReader, err := os.Open(testPath)
+	if err != nil {
+		t.Errorf("Error opening test file: %v", err)
+	}
+	defer fileReader.Close()
+
+	data, err := ProbeURLWithOptions(ctx, "pipe:0", WithStdin(fileReader), WithInputArgs("-f", "mp4"))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+
+	validateStreams(t, data)
+}
+
+func Test_ProbeReaderWithOptions_Progress(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	fileReader, err := os.Open(testPath)
+	if err != nil {
+		t.Errorf("Error opening test file: %v", err)
+	}
+	defer fileReader.Close()
+
+	var processed int64
+	data, err := ProbeReaderWithOptions(ctx, fileReader, WithProgress(func(n int64) {
+		processed = n
+	}))
+	if err != nil {
+		t.Errorf("Error getting data: %v", err)
+	}
+
+	validateData(t, data)
+
+	if processed == 0 {
+		t.Errorf("Expected progress to be reported")
+	}
+}
+
+func Test_ProbeURLWithOptions_ProgressWithoutReader(t *testing.T) {
+	_, err := ProbeURLWithOptions(context.Background(), testPath, WithProgress(func(n int64) {
+		t.Errorf("Expected no progress without a reader, got %d", n)
+	}))
+	if !errors.Is(err, ErrProgressWithoutReader) {
+		t.Errorf("Expected ErrProgressWithoutReader, got %v", err)
+	}
+}
+
+func Test_ProbeReaderWithOptions_ReaderStrategy(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	for _, strategy := range []ReaderStrategy{StrategyStdin, StrategyTempFile, StrategyAuto} {
+		fileReader, err := os.Open(testPath)
+		if err != nil {
+			t.Errorf("Error opening test file: %v", err)
+		}
+
+		data, err := ProbeReaderWithOptions(ctx, fileReader, WithReaderStrategy(strategy))
+		if err != nil {
+			t.Errorf("Error getting data with strategy %d: %v", strategy, err)
+		}
+		validateStreams(t, data)
+		if strategy == StrategyTempFile && data.Format.Filename != "pipe:" {
+			t.Errorf("Expected the pipe: filename instead of the temporary file, got %s", data.Format.Filename)
+		}
+		_ = fileReader.Close()
+	}
+}
+
+func Test_sniffSeeking(t *testing.T) {
+	content, err := ioutil.ReadFile(testPath)
+	if err != nil {
+		t.Fatalf("Error reading test file: %v", err)
+	}
+
+	reader, needsSeeking, err := sniffSeeking(bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("Error sniffing: %v", err)
+	}
+	if !needsSeeking {
+		t.Errorf("Expected MP4 file to need seeking")
+	}
+	sniffed, err := ioutil.ReadAll(reader)
+	if err != nil || !bytes.Equal(sniffed, content) {
+		t.Errorf("Expected reader to return the complete content")
+	}
+
+	_, needsSeeking, err = sniffSeeking(strings.NewReader("\x1aE\xdf\xa3 matroska"))
+	if err != nil || needsSeeking {
+		t.Errorf("Expected Matroska file to not need seeking, error: %v", err)
+	}
+}
+
+func Test_ProbeReader_Truncated(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	fileReader, err := os.Open(testPathFastStart)
+	if err != nil {
+		t.Errorf("Error opening test file: %v", err)
+	}
+	defer fileReader.Close()
+
+	// Only supply the first part of the file, like an interrupted download
+	data, err := ProbeReader(ctx, io.LimitReader(fileReader, 256*1024))
+	if err != nil && !errors.Is(err, ErrPartial) {
+		t.Fatalf("Error getting data: %v", err)
+	}
+
+	videoStream := data.FirstVideoStream()
+	if videoStream == nil {
+		t.Fatal("Video Stream was nil")
+	}
+	if videoStream.CodecName != "h264" {
+		t.Errorf("Expected video codec h264, got %s", videoStream.CodecName)
+	}
+}
+
 func Test_ProbeReader_Error(t *testing.T) {
 	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancelFn()
@@ -237,4 +865,367 @@ func Test_ProbeSideData(t *testing.T) {
 	if sideData.Rotation != -180 {
 		t.Errorf("Expected rotation to be -180, got %d", sideData.Rotation)
 	}
+
+	if rotation := videoStream.RotationClockwise(); rotation != 180 {
+		t.Errorf("Expected clockwise rotation to be 180, got %d", rotation)
+	}
+}
+
+func Test_setStreamLogs(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{{Index: 0}, {Index: 1}},
+	}
+	output := []byte(`{"frames": [
+		{"stream_index": 1, "logs": [{"context": "h264", "level": 16, "message": "error while decoding MB"}]},
+		{"stream_index": 0},
+		{"stream_index": 1, "logs": [{"context": "h264", "level": 24, "message": "concealing errors"}]}
+	]}`)
+
+	err := setStreamLogs(data, output)
+	if err != nil {
+		t.Fatalf("Error setting stream logs: %v", err)
+	}
+	if len(data.Streams[0].Logs) != 0 {
+		t.Errorf("Expected no logs for stream 0, got %v", data.Streams[0].Logs)
+	}
+	if len(data.Streams[1].Logs) != 2 || data.Streams[1].Logs[1].Message != "concealing errors" {
+		t.Errorf("Expected 2 logs for stream 1, got %v", data.Streams[1].Logs)
+	}
+}
+
+func Test_probeArgs(t *testing.T) {
+	options := newProbeOptions([]Option{
+		WithInputArgs("-f", "mp4"),
+		WithExtraArgs("-probesize", "50M"),
+	})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.HasSuffix(args, "-probesize 50M -f mp4 input.mp4") {
+		t.Errorf("Expected input args directly before the input, got: %s", args)
+	}
+}
+
+func Test_WithAnalyzeDurationAndProbeSize(t *testing.T) {
+	options := newProbeOptions([]Option{WithAnalyzeDuration(5 * time.Second), WithProbeSize(50 << 20)})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.HasSuffix(args, "-analyzeduration 5000000 -probesize 52428800 input.mp4") {
+		t.Errorf("Expected analyze duration in microseconds and probe size in bytes, got: %s", args)
+	}
+}
+
+func Test_WithShowStreamGroups(t *testing.T) {
+	options := newProbeOptions([]Option{WithShowStreamGroups()})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.Contains(args, "-show_chapters -show_stream_groups") {
+		t.Errorf("Expected stream groups to be shown, got: %s", args)
+	}
+
+	output := []byte(`{"stream_groups": [{"index": 0, "id": "0x1", "nb_streams": 2, "type": "IAMF Audio Element",
+		"components": [{"audio_element_type": 1}], "streams": [{"index": 0}, {"index": 1}]}],
+		"format": {"nb_stream_groups": 1}}`)
+	data := &ProbeData{}
+	if err := decodeOutput(output, data); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	if len(data.StreamGroups) != 1 || data.StreamGroups[0].Type != "IAMF Audio Element" ||
+		len(data.StreamGroups[0].Streams) != 2 || len(data.StreamGroups[0].Components) != 1 {
+		t.Errorf("Unexpected stream groups %+v", data.StreamGroups)
+	}
+	if data.Format.NBStreamGroups != 1 {
+		t.Errorf("Expected 1 stream group in the format, got %d", data.Format.NBStreamGroups)
+	}
+}
+
+func Test_WithAutoRetryDetection(t *testing.T) {
+	options := newProbeOptions([]Option{WithAutoRetryDetection(), WithProbeSize(1 << 20)})
+	retry := options.withIncreasedDetection()
+	if retry.autoRetryDetection {
+		t.Errorf("Expected the retry not to retry again")
+	}
+	args := strings.Join(probeArgs(retry, "input.ts"), " ")
+	if !strings.HasSuffix(args, "-probesize 1048576 -probesize 10485760 -analyzeduration 50000000 input.ts") {
+		t.Errorf("Expected increased probe size and analyze duration, got: %s", args)
+	}
+	if len(options.inputArgs) != 2 {
+		t.Errorf("Expected the original options to be unchanged, got %v", options.inputArgs)
+	}
+
+	options = newProbeOptions([]Option{WithAutoRetryDetection(), WithExtraArgs("-probesize", "50M", "-analyzeduration", "1.5Mi")})
+	args = strings.Join(probeArgs(options.withIncreasedDetection(), "input.ts"), " ")
+	if !strings.HasSuffix(args, "-probesize 500000000 -analyzeduration 15728640 input.ts") {
+		t.Errorf("Expected the suffixed values to be increased, got: %s", args)
+	}
+
+	options = newProbeOptions([]Option{WithAutoRetryDetection(), WithExtraArgs("-probesize", "lots")})
+	if retry := options.withIncreasedDetection(); retry != nil {
+		t.Errorf("Expected no retry with an unparsable probe size, got %v", retry.inputArgs)
+	}
+
+	if !detectionFailed(&ProbeData{}) {
+		t.Errorf("Expected detection to fail without streams")
+	}
+	if !detectionFailed(&ProbeData{Streams: []*Stream{{CodecType: "video", CodecName: "h264"}}}) {
+		t.Errorf("Expected detection to fail without video dimensions")
+	}
+	if detectionFailed(&ProbeData{Streams: []*Stream{{CodecType: "audio", CodecName: "aac", SampleRate: "48000"}}}) {
+		t.Errorf("Expected detection to succeed")
+	}
+}
+
+func Test_WithDecryptionKey(t *testing.T) {
+	const key = "00112233445566778899aabbccddeeff"
+	options := newProbeOptions([]Option{WithDecryptionKey("", key)})
+	if args := strings.Join(probeArgs(options, "input.mp4"), " "); !strings.HasSuffix(args, "-decryption_key "+key+" input.mp4") {
+		t.Errorf("Expected the decryption key before the input, got: %s", args)
+	}
+
+	options = newProbeOptions([]Option{WithDecryptionKey("A0B1C2D3-0000-1111-2222-333344445555", key)})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.HasSuffix(args, "-decryption_keys a0b1c2d3000011112222333344445555="+key+" input.mp4") {
+		t.Errorf("Expected the decryption key by key ID before the input, got: %s", args)
+	}
+}
+
+func Test_WithoutPrivateData(t *testing.T) {
+	options := newProbeOptions([]Option{WithoutPrivateData()})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.Contains(args, "-noshow_private_data") {
+		t.Errorf("Expected private data to be left out, got: %s", args)
+	}
+
+	options = newProbeOptions(nil)
+	if args = strings.Join(probeArgs(options, "input.mp4"), " "); strings.Contains(args, "show_private_data") {
+		t.Errorf("Expected no private data option by default, got: %s", args)
+	}
+}
+
+func Test_WithShowOptionalFields(t *testing.T) {
+	options := newProbeOptions([]Option{WithShowOptionalFields("always")})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.Contains(args, "-show_optional_fields always") {
+		t.Errorf("Expected optional fields to always be shown, got: %s", args)
+	}
+
+	options = newProbeOptions(nil)
+	if args = strings.Join(probeArgs(options, "input.mp4"), " "); strings.Contains(args, "-show_optional_fields") {
+		t.Errorf("Expected no optional fields option by default, got: %s", args)
+	}
+}
+
+func Test_WithShowDataHash(t *testing.T) {
+	options := newProbeOptions([]Option{WithShowDataHash("sha256")})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.Contains(args, "-show_data_hash SHA256") {
+		t.Errorf("Expected a SHA256 data hash, got: %s", args)
+	}
+
+	for _, algorithm := range []string{"", "SHA1", "-show_frames"} {
+		options = newProbeOptions([]Option{WithShowDataHash(algorithm)})
+		if args = strings.Join(probeArgs(options, "input.mp4"), " "); strings.Contains(args, "-show_data_hash") {
+			t.Errorf("Expected no data hash for algorithm %q, got: %s", algorithm, args)
+		}
+	}
+
+	options = newProbeOptions(nil)
+	if args = strings.Join(probeArgs(options, "input.mp4"), " "); strings.Contains(args, "-show_data_hash") {
+		t.Errorf("Expected no data hash by default, got: %s", args)
+	}
+}
+
+func Test_WithProtocolWhitelist(t *testing.T) {
+	options := newProbeOptions([]Option{WithProtocolWhitelist("https", "tls", "tcp")})
+	args := strings.Join(probeArgs(options, "https://example.com/test.m3u8"), " ")
+	if !strings.HasSuffix(args, "-protocol_whitelist https,tls,tcp https://example.com/test.m3u8") {
+		t.Errorf("Expected the protocol whitelist before the input, got: %s", args)
+	}
+}
+
+func Test_WithProbeFirstKeyframeOnly(t *testing.T) {
+	options := newProbeOptions([]Option{WithProbeFirstKeyframeOnly()})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.HasSuffix(args, "-read_intervals %+#1 -probesize 32 -analyzeduration 0 input.mp4") {
+		t.Errorf("Expected a minimal analysis and read intervals limited to the first packet, got: %s", args)
+	}
+	if strings.Contains(args, "-skip_frame") {
+		t.Errorf("Expected no frame skipping without frames, got: %s", args)
+	}
+
+	options = newProbeOptions([]Option{WithProbeSize(1 << 20), WithProbeFirstKeyframeOnly(), WithShowLog(24)})
+	args = strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.HasSuffix(args, "-probesize 32 -analyzeduration 0 -probesize 1048576 -skip_frame nokey input.mp4") {
+		t.Errorf("Expected the explicit probe size to take precedence and key frames only, got: %s", args)
+	}
+}
+
+func Test_SetDefaultArgs(t *testing.T) {
+	SetDefaultArgs([]string{"-probesize", "50M"})
+	defer SetDefaultArgs(nil)
+
+	options := newProbeOptions([]Option{WithExtraArgs("-probesize", "10M")})
+	args := strings.Join(probeArgs(options, "input.mp4"), " ")
+	if !strings.HasSuffix(args, "-probesize 50M -probesize 10M input.mp4") {
+		t.Errorf("Expected default args before the per-call args, got: %s", args)
+	}
+
+	SetDefaultArgs([]string{"-of", "xml"})
+	if _, err := ProbeURL(context.Background(), testPath); !errors.Is(err, ErrNonJSONOutputFormat) {
+		t.Errorf("Expected ErrNonJSONOutputFormat for default args, got %v", err)
+	}
+}
+
+func Test_pcmInputArgs(t *testing.T) {
+	options := newProbeOptions([]Option{WithInputArgs(pcmInputArgs("s16le", 44100, 2)...)})
+	args := strings.Join(probeArgs(options, "pipe:"), " ")
+	if !strings.HasSuffix(args, "-f s16le -sample_rate 44100 -ch_layout 2C pipe:") {
+		t.Errorf("Expected raw PCM input args before the input, got: %s", args)
+	}
+}
+
+func Test_ProbeRawPCM_KeepsOptions(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	// Spare capacity in the options must not be written to
+	opts := make([]Option, 1, 2)
+	opts[0] = WithTimeout(time.Second)
+	_, _ = ProbeRawPCM(ctx, bytes.NewReader(make([]byte, 4096)), "s16le", 44100, 2, opts...)
+	if opts[:2][1] != nil {
+		t.Errorf("Expected the options of the caller to be unchanged")
+	}
+}
+
+func Test_imageSequenceInputArgs(t *testing.T) {
+	options := newProbeOptions([]Option{WithInputArgs(imageSequenceInputArgs(29.97)...)})
+	args := strings.Join(probeArgs(options, "frame_%04d.png"), " ")
+	if !strings.HasSuffix(args, "-f image2 -framerate 29.97 frame_%04d.png") {
+		t.Errorf("Expected image sequence input args before the input, got: %s", args)
+	}
+}
+
+func Test_ProbeImageSequence_KeepsOptions(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	// Spare capacity in the options must not be written to
+	opts := make([]Option, 1, 3)
+	opts[0] = WithTimeout(time.Second)
+	_, _ = ProbeImageSequence(ctx, "frame_%04d.png", 25, opts...)
+	if extra := opts[:3]; extra[1] != nil || extra[2] != nil {
+		t.Errorf("Expected the options of the caller to be unchanged")
+	}
+}
+
+func Test_WithShowEntries(t *testing.T) {
+	options := newProbeOptions([]Option{
+		WithShowEntries(map[string][]string{
+			"stream":      {"codec_name", "width"},
+			"format":      {"duration", "bit_rate"},
+			"stream_tags": nil,
+		}),
+	})
+	const expected = "format=duration,bit_rate:stream=codec_name,width:stream_tags"
+	if options.showEntries != expected {
+		t.Errorf("Expected show entries %s, got %s", expected, options.showEntries)
+	}
+}
+
+func Test_ProbeReaders(t *testing.T) {
+	ctx, cancelFn := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancelFn()
+
+	var readers []io.Reader
+	for _, path := range []string{testPath, testPathError, testPath} {
+		fileReader, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("Error opening test file: %v", err)
+		}
+		defer fileReader.Close()
+		readers = append(readers, fileReader)
+	}
+
+	results, errs := ProbeReaders(ctx, readers, 2)
+	if len(results) != 3 || len(errs) != 3 {
+		t.Fatalf("Expected 3 results, got %d results and %d errors", len(results), len(errs))
+	}
+	for _, i := range []int{0, 2} {
+		if errs[i] != nil {
+			t.Errorf("Error getting data for reader %d: %v", i, errs[i])
+		}
+		validateStreams(t, results[i])
+	}
+	if errs[1] == nil {
+		t.Errorf("No error reading bad asset")
+	}
+}
+
+func Test_ExecError_StderrLines(t *testing.T) {
+	execErr := &ExecError{
+		Stderr: "[mov,mp4,m4a,3gp,3g2,mj2 @ 0x1] moov atom not found\r\n\ntest.mp4: Invalid data found when processing input\n",
+	}
+	lines := execErr.StderrLines()
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", lines)
+	}
+	if lines[1] != "test.mp4: Invalid data found when processing input" {
+		t.Errorf("Unexpected second line: %q", lines[1])
+	}
+}
+
+func Test_limitedBuffer_grow(t *testing.T) {
+	buf := limitedBuffer{limit: 1024}
+	buf.grow(1 << 20)
+	if buf.buf.Cap() < 1024 || buf.buf.Cap() >= 1<<20 {
+		t.Errorf("Expected the buffer to grow up to its limit, got capacity %d", buf.buf.Cap())
+	}
+}
+
+func Test_decodeOutput(t *testing.T) {
+	output := []byte("WARNING: vendor patch active\n{\"format\": {\"format_name\": \"mov\"}}\nDone.\n")
+	data := &ProbeData{}
+	err := decodeOutput(output, data)
+	if err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	if data.Format == nil || data.Format.FormatName != "mov" {
+		t.Errorf("Expected format to be decoded, got %+v", data.Format)
+	}
+}
+
+func Test_decodeOutput_Compact(t *testing.T) {
+	// Output of -print_format json=c=1, which puts every section on a single line
+	output := []byte(`{
+    "streams": [
+        {"index": 0, "codec_name": "h264", "codec_type": "video", "width": 1280, "height": 720, "tags": {"language": "und"}}
+    ],
+    "chapters": [
+
+    ],
+    "format": {"filename": "test.mp4", "nb_streams": 1, "format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "5.312000"}
+}
+`)
+	data := &ProbeData{}
+	err := decodeOutput(output, data)
+	if err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	if len(data.Streams) != 1 || data.Streams[0].Width != 1280 || data.Format == nil || data.Format.DurationSeconds != 5.312 {
+		t.Errorf("Expected compact output to be decoded, got %+v", data)
+	}
+}
+
+func Benchmark_limitedBuffer(b *testing.B) {
+	const outputSize = 16 << 20
+	chunk := make([]byte, 32<<10)
+
+	for _, hint := range []int{0, outputSize} {
+		b.Run(fmt.Sprintf("hint=%d", hint), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				buf := limitedBuffer{limit: DefaultMaxOutputBytes}
+				buf.grow(hint)
+				for written := 0; written < outputSize; written += len(chunk) {
+					_, _ = buf.Write(chunk)
+				}
+			}
+		})
+	}
 }
@@ -0,0 +1,52 @@
+package ffprobe
+
+import (
+	"time"
+)
+
+// MinimalProbe is a small summary of the probe data, which marshals to compact JSON for caching large amounts of
+// probes. Its shape is stable: fields may be added in the future, but existing fields will not change.
+type MinimalProbe struct {
+	DurationSeconds float64         `json:"duration"`
+	FormatName      string          `json:"format_name"`
+	Streams         []MinimalStream `json:"streams"`
+}
+
+// MinimalStream is a small summary of a stream, see MinimalProbe.
+type MinimalStream struct {
+	Index     int    `json:"index"`
+	CodecType string `json:"codec_type"`
+	CodecName string `json:"codec_name,omitempty"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+}
+
+// Duration returns the duration of the media file as a time.Duration
+func (m *MinimalProbe) Duration() time.Duration {
+	return time.Duration(m.DurationSeconds * float64(time.Second))
+}
+
+// Minimal returns a small summary of the probe data with the duration, format name and the type, codec and dimensions
+// of every stream.
+func (p *ProbeData) Minimal() MinimalProbe {
+	var minimal MinimalProbe
+	if p.Format != nil {
+		minimal.DurationSeconds = p.Format.DurationSeconds
+		minimal.FormatName = p.Format.FormatName
+	}
+
+	minimal.Streams = make([]MinimalStream, 0, len(p.Streams))
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		minimal.Streams = append(minimal.Streams, MinimalStream{
+			Index:     s.Index,
+			CodecType: s.CodecType,
+			CodecName: s.CodecName,
+			Width:     s.Width,
+			Height:    s.Height,
+		})
+	}
+	return minimal
+}
@@ -0,0 +1,10 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !illumos && !linux && !netbsd && !openbsd && !solaris
+// +build !aix,!darwin,!dragonfly,!freebsd,!illumos,!linux,!netbsd,!openbsd,!solaris
+
+package ffprobe
+
+// nicenessSupported is false, process niceness is not supported on this platform, such as Windows
+const nicenessSupported = false
+
+// setNiceness is never called, as runCommand returns ErrNicenessUnsupported when nicenessSupported is false
+var setNiceness func(pid, niceness int) error
@@ -0,0 +1,15 @@
+//go:build aix || darwin || dragonfly || freebsd || illumos || linux || netbsd || openbsd || solaris
+// +build aix darwin dragonfly freebsd illumos linux netbsd openbsd solaris
+
+package ffprobe
+
+import (
+	"syscall"
+)
+
+// nicenessSupported is true, as setpriority is available on this platform
+const nicenessSupported = true
+
+func setNiceness(pid, niceness int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, niceness)
+}
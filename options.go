@@ -0,0 +1,460 @@
+package ffprobe
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultMaxOutputBytes is the default maximum size of the ffprobe output, see WithMaxOutputBytes.
+const DefaultMaxOutputBytes = 256 << 20
+
+// ReaderStrategy determines how the content of a reader is passed to ffprobe, see WithReaderStrategy.
+type ReaderStrategy int
+
+const (
+	// StrategyStdin pipes the reader to ffprobe, which does not allow ffprobe to seek
+	StrategyStdin ReaderStrategy = iota
+	// StrategyTempFile copies the reader to a temporary file, which allows ffprobe to seek. The filename of the
+	// format is reported as "pipe:", as the temporary file is removed once the probe returns.
+	StrategyTempFile
+	// StrategyAuto uses a temporary file for formats that are known to need seeking, and stdin for others.
+	// Currently the ISO BMFF formats (MP4, MOV, M4A, 3GP) use a temporary file, as their stream information
+	// can be stored at the end of the file.
+	StrategyAuto
+)
+
+// Option is used to configure a probe, see ProbeURLWithOptions and ProbeReaderWithOptions.
+type Option func(opts *probeOptions)
+
+// probeOptions holds the configuration of a single probe
+type probeOptions struct {
+	defaultArgs        []string
+	extraArgs          []string
+	elapsed            *time.Duration
+	countPackets       bool
+	maxOutputBytes     int64
+	showLog            bool
+	logLevel           int
+	pipeFD             int
+	inputArgs          []string
+	showEntries        string
+	niceness           int
+	sections           []string
+	stdin              io.Reader
+	progress           func(processed int64)
+	dataHash           string
+	readerStrategy     ReaderStrategy
+	noPrivateData      bool
+	optionalFields     string
+	deadline           time.Time
+	readIntervals      string
+	keyframesOnly      bool
+	partialOnTimeout   bool
+	outputBufferHint   int
+	autoRetryDetection bool
+	tracer             func(ctx context.Context, args []string) func(err error)
+	allowedSchemes     []string
+	logger             func(ctx context.Context, args []string, elapsed time.Duration, err error)
+}
+
+func newProbeOptions(opts []Option) *probeOptions {
+	options := &probeOptions{
+		defaultArgs:    getDefaultArgs(),
+		maxOutputBytes: DefaultMaxOutputBytes,
+		sections:       []string{"-show_format", "-show_streams", "-show_chapters"},
+	}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// withDeadline returns a context with the deadline of the options, or the context as is without deadline.
+func (o *probeOptions) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, o.deadline)
+}
+
+// Defaults of the -probesize and -analyzeduration input options of ffprobe, in bytes and microseconds
+const (
+	defaultProbeSize       = 5000000
+	defaultAnalyzeDuration = 5000000
+)
+
+// detectionRetryFactor is the factor by which WithAutoRetryDetection increases the probe size and analyze duration
+const detectionRetryFactor = 10
+
+// withIncreasedDetection returns a copy of the options for retrying the probe with the probe size and analyze
+// duration increased by detectionRetryFactor. The copy does not retry again. When a probe size or analyze duration
+// passed in the arguments cannot be parsed, nil is returned, as the retry could use less than was asked for.
+func (o *probeOptions) withIncreasedDetection() *probeOptions {
+	retry := *o
+	retry.autoRetryDetection = false
+
+	probeSize, analyzeDuration := int64(defaultProbeSize), int64(defaultAnalyzeDuration)
+	for _, args := range [][]string{o.defaultArgs, o.extraArgs, o.inputArgs} {
+		for i := 0; i < len(args)-1; i++ {
+			if args[i] != "-probesize" && args[i] != "-analyzeduration" {
+				continue
+			}
+			value, err := parseOptionInt(args[i+1])
+			if err != nil {
+				return nil
+			}
+			if args[i] == "-probesize" {
+				probeSize = value
+			} else {
+				analyzeDuration = value
+			}
+		}
+	}
+
+	retry.inputArgs = append(append([]string(nil), o.inputArgs...),
+		"-probesize", strconv.FormatInt(probeSize*detectionRetryFactor, 10),
+		"-analyzeduration", strconv.FormatInt(analyzeDuration*detectionRetryFactor, 10),
+	)
+	return &retry
+}
+
+// optionSuffixes are the multipliers of the suffixes ffmpeg allows on numeric options, like "50M". A suffix followed
+// by "i", like "50Mi", is a power of 1024 instead.
+var optionSuffixes = map[byte]int{'K': 1, 'k': 1, 'M': 2, 'G': 3, 'T': 4}
+
+// parseOptionInt parses the value of a numeric ffmpeg option, which may have a K, M, G or T suffix
+func parseOptionInt(str string) (int64, error) {
+	number, base, power := str, 1000.0, 0
+	if strings.HasSuffix(number, "i") {
+		number, base = number[:len(number)-1], 1024
+	}
+	if len(number) > 0 {
+		if p, ok := optionSuffixes[number[len(number)-1]]; ok {
+			number, power = number[:len(number)-1], p
+		} else if base == 1024 {
+			return 0, fmt.Errorf("invalid option value: %q", str)
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("option value parsing error (%v): %w", str, err)
+	}
+	return int64(value * math.Pow(base, float64(power))), nil
+}
+
+// withSections replaces the arguments selecting the sections ffprobe shows in full
+func withSections(sections ...string) Option {
+	return func(opts *probeOptions) {
+		opts.sections = sections
+	}
+}
+
+// WithExtraArgs supplies additional parameters to the ffprobe command, in the same way as the extraFFProbeOptions
+// parameter of ProbeURL and ProbeReader.
+func WithExtraArgs(args ...string) Option {
+	return func(opts *probeOptions) {
+		opts.extraArgs = append(opts.extraArgs, args...)
+	}
+}
+
+// WithInputArgs supplies options for the input to the ffprobe command, such as "-f" to force the input format or
+// protocol options like "-timeout". These are placed directly before the input, after all other parameters.
+// Note that ffmpeg has no option to select the IP address family used for network protocols, so forcing IPv4 or
+// IPv6 is only possible by resolving the host beforehand.
+func WithInputArgs(args ...string) Option {
+	return func(opts *probeOptions) {
+		opts.inputArgs = append(opts.inputArgs, args...)
+	}
+}
+
+// WithProtocolWhitelist restricts the protocols ffprobe may use to open the input and any resources it refers to, such
+// as the segments of a HLS playlist, using the -protocol_whitelist input option, e.g. "https", "tls" and "tcp".
+func WithProtocolWhitelist(protocols ...string) Option {
+	return WithInputArgs("-protocol_whitelist", strings.Join(protocols, ","))
+}
+
+// WithAllowedInputSchemes makes ProbeURLWithOptions return ErrInputSchemeNotAllowed without starting ffprobe when the
+// protocol of the URL is not one of schemes, compared case insensitively. Plain paths use the "file" scheme, so
+// "file" must be allowed to probe local files. Use WithProtocolWhitelist as well to also restrict the protocols used
+// by the resources the input refers to, as only the URL itself is checked.
+func WithAllowedInputSchemes(schemes ...string) Option {
+	return func(opts *probeOptions) {
+		opts.allowedSchemes = append(opts.allowedSchemes, schemes...)
+	}
+}
+
+// WithAnalyzeDuration sets how much of the input ffprobe analyzes to determine the stream information, passed as the
+// -analyzeduration input option in the microseconds it expects.
+func WithAnalyzeDuration(duration time.Duration) Option {
+	return WithInputArgs("-analyzeduration", strconv.FormatInt(duration.Microseconds(), 10))
+}
+
+// WithProbeSize sets how many bytes of the input ffprobe reads to determine the stream information, passed as the
+// -probesize input option.
+func WithProbeSize(bytes int64) Option {
+	return WithInputArgs("-probesize", strconv.FormatInt(bytes, 10))
+}
+
+// WithDecryptionKey makes ffprobe decrypt Common Encryption (CENC) protected MP4 streams with the given hexadecimal
+// key, so encrypted content can be probed. Without keyID the key is used for all streams with the -decryption_key
+// input option. With keyID, e.g. "00112233-4455-6677-8899-aabbccddeeff", the key is only used for the streams with
+// that key ID, using the -decryption_keys input option of ffprobe 7.1 and newer. The scheme, like AES-CTR, is read
+// from the file, as ffprobe has no option to select it.
+func WithDecryptionKey(keyID, key string) Option {
+	if keyID == "" {
+		return WithInputArgs("-decryption_key", key)
+	}
+	keyID = strings.ToLower(strings.ReplaceAll(keyID, "-", ""))
+	return WithInputArgs("-decryption_keys", keyID+"="+key)
+}
+
+// WithTiming stores the time the ffprobe process took to execute in elapsed once the probe is done.
+// Only the execution of the process is measured, building the command and parsing the output are excluded.
+func WithTiming(elapsed *time.Duration) Option {
+	return func(opts *probeOptions) {
+		opts.elapsed = elapsed
+	}
+}
+
+// WithLogger calls logger after every probe has completed, with the context of the probe, the arguments ffprobe was
+// called with, the time the probe took and the resulting error, which is nil on success. Unlike WithTiming, the
+// elapsed time includes parsing the output.
+func WithLogger(logger func(ctx context.Context, args []string, elapsed time.Duration, err error)) Option {
+	return func(opts *probeOptions) {
+		opts.logger = logger
+	}
+}
+
+// WithTracer calls tracer right before the ffprobe process is started, with the context of the probe and the
+// arguments ffprobe is called with. The function it returns is called once the process exited, with the error of the
+// process, which is nil when it succeeded. This allows wrapping the execution in a tracing span, e.g.:
+//
+//	ffprobe.WithTracer(func(ctx context.Context, args []string) func(err error) {
+//		_, span := tracer.Start(ctx, "ffprobe")
+//		return func(err error) {
+//			if err != nil {
+//				span.RecordError(err)
+//			}
+//			span.End()
+//		}
+//	})
+func WithTracer(tracer func(ctx context.Context, args []string) func(err error)) Option {
+	return func(opts *probeOptions) {
+		opts.tracer = tracer
+	}
+}
+
+// WithCountPackets makes ffprobe read the whole file to count the packets of every stream, see
+// Stream.EstimatedDurationFromPackets. Note that this is a lot slower than a regular probe.
+func WithCountPackets() Option {
+	return func(opts *probeOptions) {
+		opts.countPackets = true
+	}
+}
+
+// WithMaxOutputBytes limits the amount of output read from ffprobe to protect against running out of memory on
+// pathological files. The ffprobe process is killed and ErrOutputTooLarge is returned once the limit is exceeded.
+// The default is DefaultMaxOutputBytes, a value of zero or less disables the limit.
+func WithMaxOutputBytes(n int64) Option {
+	return func(opts *probeOptions) {
+		opts.maxOutputBytes = n
+	}
+}
+
+// WithShowLog collects the decoder log messages up to the given ffmpeg log level (e.g. 24 for warnings) into the
+// Logs of each stream. As ffprobe only reports these while decoding frames, this makes ffprobe decode all frames
+// of the file, which is a lot slower than a regular probe and produces a lot more output.
+func WithShowLog(level int) Option {
+	return func(opts *probeOptions) {
+		opts.showLog = true
+		opts.logLevel = level
+	}
+}
+
+// WithPipeFD makes ProbeReaderWithOptions pass the reader to ffprobe on the given file descriptor instead of stdin,
+// using the "pipe:<fd>" input. File descriptors 0 to 2 are reserved for stdin, stdout and stderr, so any lower value
+// means stdin is used. This is not supported on Windows.
+func WithPipeFD(fd int) Option {
+	return func(opts *probeOptions) {
+		opts.pipeFD = fd
+	}
+}
+
+// WithShowEntries limits the entries shown by ffprobe to the given fields per section, e.g. {"format": {"duration"},
+// "stream": {"codec_name", "width"}} results in "-show_entries format=duration:stream=codec_name,width".
+// A section without fields shows all of its entries. The sections are sorted by name for a stable argument.
+func WithShowEntries(sections map[string][]string) Option {
+	return func(opts *probeOptions) {
+		names := make([]string, 0, len(sections))
+		for name := range sections {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		entries := make([]string, 0, len(names))
+		for _, name := range names {
+			if len(sections[name]) == 0 {
+				entries = append(entries, name)
+				continue
+			}
+			entries = append(entries, name+"="+strings.Join(sections[name], ","))
+		}
+		opts.showEntries = strings.Join(entries, ":")
+	}
+}
+
+// WithOutputBufferHint pre-allocates the buffer for the ffprobe output with room for the given number of bytes, at
+// most the maximum output size. When large outputs are expected, e.g. with WithShowLog, this avoids growing the
+// buffer repeatedly while ffprobe writes its output.
+func WithOutputBufferHint(bytes int) Option {
+	return func(opts *probeOptions) {
+		opts.outputBufferHint = bytes
+	}
+}
+
+// WithNiceness runs the ffprobe process with the given niceness, e.g. 10 to run background scans at a low priority.
+// Negative values, which raise the priority, usually require elevated privileges. This is only supported on Unix
+// platforms, on others, such as Windows, the probe fails with ErrNicenessUnsupported without starting ffprobe.
+func WithNiceness(n int) Option {
+	return func(opts *probeOptions) {
+		opts.niceness = n
+	}
+}
+
+// WithStdin supplies the reader to use as stdin of the ffprobe process for ProbeURLWithOptions. This allows probing
+// an explicit "pipe:0" URL with options of your own. ProbeReaderWithOptions always uses its reader as stdin.
+func WithStdin(reader io.Reader) Option {
+	return func(opts *probeOptions) {
+		opts.stdin = reader
+	}
+}
+
+// WithProgress calls the progress function with the total number of bytes read by ffprobe from the reader so far,
+// which allows showing progress for long probes like WithCountPackets. This only works when probing a reader, as
+// ffprobe itself does not report progress: ProbeURLWithOptions returns ErrProgressWithoutReader for it unless the
+// reader is supplied with WithStdin. The function is called from a separate goroutine.
+func WithProgress(progress func(processed int64)) Option {
+	return func(opts *probeOptions) {
+		opts.progress = progress
+	}
+}
+
+// WithShowDataHash makes ffprobe include a hash of the binary data using the given algorithm, e.g. "MD5", "SHA256" or
+// "CRC32". For streams this is the hash of the codec extradata, see Stream.ExtradataHash. This is off by default, an
+// empty algorithm or one ffprobe does not support leaves it off, so ffprobe does not fail on it.
+func WithShowDataHash(algorithm string) Option {
+	return func(opts *probeOptions) {
+		opts.dataHash = ""
+		for _, name := range dataHashAlgorithms {
+			if strings.EqualFold(algorithm, name) {
+				opts.dataHash = name
+			}
+		}
+	}
+}
+
+// dataHashAlgorithms are the hash algorithms supported by ffprobe, which it matches case insensitively.
+var dataHashAlgorithms = []string{
+	"MD5", "murmur3", "RIPEMD128", "RIPEMD160", "RIPEMD256", "RIPEMD320", "SHA160", "SHA224", "SHA256",
+	"SHA512/224", "SHA512/256", "SHA384", "SHA512", "CRC32", "adler32",
+}
+
+// WithReaderStrategy determines how ProbeReaderWithOptions passes the content of the reader to ffprobe.
+// The default is StrategyStdin.
+func WithReaderStrategy(strategy ReaderStrategy) Option {
+	return func(opts *probeOptions) {
+		opts.readerStrategy = strategy
+	}
+}
+
+// WithConcatDemuxer probes the input as a concat demuxer list file, which describes a concatenation of segments.
+// The reported format duration is then the total duration of all segments. The list may contain absolute paths.
+// See https://ffmpeg.org/ffmpeg-formats.html#concat-1
+func WithConcatDemuxer() Option {
+	return WithInputArgs("-f", "concat", "-safe", "0")
+}
+
+// WithoutPrivateData makes ffprobe leave out the private data of the codecs and formats, which can be large for
+// some streams. This reduces the output size and speeds up parsing when those fields are not needed.
+func WithoutPrivateData() Option {
+	return func(opts *probeOptions) {
+		opts.noPrivateData = true
+	}
+}
+
+// WithShowOptionalFields controls whether ffprobe includes fields without a meaningful value, like an "N/A" bit rate,
+// in its output: "always" includes them, "never" leaves them out and "auto", the default, leaves out some of them.
+// This makes the presence of fields independent of the ffprobe defaults, it requires ffprobe 5.0 or newer. Numeric
+// fields that ffprobe prints as "N/A", like the start_pts of a stream without timestamps, are decoded as 0.
+func WithShowOptionalFields(mode string) Option {
+	return func(opts *probeOptions) {
+		opts.optionalFields = mode
+	}
+}
+
+// WithPartialOnTimeout makes the probe return the data ffprobe had already written when it is killed because the
+// deadline of the context passed, e.g. set with WithTimeout. The data is only returned when ffprobe wrote a complete
+// JSON object, together with an error wrapping ErrPartial. Without this option, or when the output is incomplete,
+// the error of the killed process is returned without data.
+func WithPartialOnTimeout() Option {
+	return func(opts *probeOptions) {
+		opts.partialOnTimeout = true
+	}
+}
+
+// WithShowStreamGroups makes ffprobe report the stream groups of the media file in ProbeData.StreamGroups, like the
+// audio elements and mix presentations of IAMF immersive audio. This requires ffprobe 7.0 or newer.
+func WithShowStreamGroups() Option {
+	return func(opts *probeOptions) {
+		opts.sections = append(opts.sections[:len(opts.sections):len(opts.sections)], "-show_stream_groups")
+	}
+}
+
+// WithAutoRetryDetection makes the probe run ffprobe a second time with a 10 times larger probe size and analyze
+// duration when the first run found no streams, or audio or video streams without their codec parameters. This
+// often helps for MPEG-TS captures and other streams with sparse headers. When the retry fails, the result of the
+// first run is returned. Probes of a reader piped to stdin are not retried, as the reader cannot be read twice.
+// A probe size or analyze duration passed as argument, like "-probesize 50M", is increased as well, the probe is not
+// retried when such a value cannot be parsed.
+func WithAutoRetryDetection() Option {
+	return func(opts *probeOptions) {
+		opts.autoRetryDetection = true
+	}
+}
+
+// WithProbeFirstKeyframeOnly makes ffprobe do as little work as possible when only the codecs and dimensions are
+// needed. The stream analysis is limited to the minimum with "-probesize 32" and "-analyzeduration 0", which
+// WithProbeSize and WithAnalyzeDuration still override, and the packets and frames read for WithCountPackets or
+// WithShowLog are limited to the first packet with -read_intervals "%+#1", with only key frames decoded. Containers
+// that store the codec parameters in their header, like MP4 and MKV, are still fully described, but streams with
+// sparse headers like MPEG-TS may be reported without their codec parameters or not at all. Values ffprobe derives
+// from the analysis or the packets, like the duration and bit rate, may be missing or inaccurate.
+func WithProbeFirstKeyframeOnly() Option {
+	return func(opts *probeOptions) {
+		// Prepended so a probe size or analyze duration passed explicitly comes later and takes precedence
+		opts.inputArgs = append([]string{"-probesize", "32", "-analyzeduration", "0"}, opts.inputArgs...)
+		opts.readIntervals = "%+#1"
+		opts.keyframesOnly = true
+	}
+}
+
+// WithDeadline kills the ffprobe process when it is still running at the given time, even if the context passed to
+// the probe function has no deadline. When both have a deadline, the earliest one applies.
+func WithDeadline(deadline time.Time) Option {
+	return func(opts *probeOptions) {
+		opts.deadline = deadline
+	}
+}
+
+// WithTimeout kills the ffprobe process when it is still running after the given duration, see WithDeadline.
+func WithTimeout(timeout time.Duration) Option {
+	return func(opts *probeOptions) {
+		opts.deadline = time.Now().Add(timeout)
+	}
+}
@@ -1,9 +1,20 @@
 package ffprobe
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// ErrFieldNotFound is a sentinel error used when a field needed for a calculation is missing from the ffprobe data
+var ErrFieldNotFound = errors.New("field not found")
+
 // StreamType represents a media stream type like video, audio, subtitles, etc
 type StreamType string
 
@@ -22,11 +33,54 @@ const (
 	StreamAttachment StreamType = "attachment"
 )
 
+// String returns the name of the stream type as used by ffprobe, e.g. "video", or "any" for StreamAny
+func (t StreamType) String() string {
+	if t == StreamAny {
+		return "any"
+	}
+	return string(t)
+}
+
+// ParseStreamType returns the stream type for the given codec_type, like "video" or "audio", case insensitively.
+// The name "any" returned by StreamType.String is parsed as StreamAny. False is returned for unknown stream types.
+func ParseStreamType(s string) (StreamType, bool) {
+	switch StreamType(strings.ToLower(s)) {
+	case StreamVideo:
+		return StreamVideo, true
+	case StreamAudio:
+		return StreamAudio, true
+	case StreamSubtitle:
+		return StreamSubtitle, true
+	case StreamData:
+		return StreamData, true
+	case StreamAttachment:
+		return StreamAttachment, true
+	case "any":
+		return StreamAny, true
+	}
+	return StreamAny, false
+}
+
+// MediaKind represents the coarse kind of a media file, see ProbeData.Kind
+type MediaKind string
+
+const (
+	// MediaUnknown means the kind of media could not be determined
+	MediaUnknown MediaKind = "unknown"
+	// MediaVideo is a file with moving video
+	MediaVideo MediaKind = "video"
+	// MediaAudio is a file with audio, and at most attached pictures such as cover art
+	MediaAudio MediaKind = "audio"
+	// MediaImage is a still image
+	MediaImage MediaKind = "image"
+)
+
 // ProbeData is the root json data structure returned by an ffprobe.
 type ProbeData struct {
-	Streams  []*Stream  `json:"streams"`
-	Format   *Format    `json:"format"`
-	Chapters []*Chapter `json:"chapters"`
+	Streams      []*Stream      `json:"streams"`
+	Format       *Format        `json:"format"`
+	Chapters     []*Chapter     `json:"chapters"`
+	StreamGroups []*StreamGroup `json:"stream_groups,omitempty"`
 }
 
 // Format is a json data structure to represent formats
@@ -34,6 +88,7 @@ type Format struct {
 	Filename         string      `json:"filename"`
 	NBStreams        int         `json:"nb_streams"`
 	NBPrograms       int         `json:"nb_programs"`
+	NBStreamGroups   int         `json:"nb_stream_groups,omitempty"`
 	FormatName       string      `json:"format_name"`
 	FormatLongName   string      `json:"format_long_name"`
 	StartTimeSeconds float64     `json:"start_time,string"`
@@ -66,6 +121,7 @@ type Stream struct {
 	BitRate            string            `json:"bit_rate"`
 	BitsPerRawSample   string            `json:"bits_per_raw_sample"`
 	NbFrames           string            `json:"nb_frames"`
+	NbReadPackets      string            `json:"nb_read_packets,omitempty"`
 	Disposition        StreamDisposition `json:"disposition,omitempty"`
 	TagList            Tags              `json:"tags"`
 	Tags               StreamTags        `json:"-"` // Deprecated: Use TagList instead
@@ -74,6 +130,8 @@ type Stream struct {
 	Width              int               `json:"width"`
 	Height             int               `json:"height"`
 	HasBFrames         int               `json:"has_b_frames,omitempty"`
+	Refs               int               `json:"refs,omitempty"`
+	ClosedCaptions     int               `json:"closed_captions,omitempty"`
 	SampleAspectRatio  string            `json:"sample_aspect_ratio,omitempty"`
 	DisplayAspectRatio string            `json:"display_aspect_ratio,omitempty"`
 	PixFmt             string            `json:"pix_fmt,omitempty"`
@@ -87,6 +145,94 @@ type Stream struct {
 	ChannelLayout      string            `json:"channel_layout,omitempty"`
 	BitsPerSample      int               `json:"bits_per_sample,omitempty"`
 	SideDataList       SideDataList      `json:"side_data_list,omitempty"`
+	ExtradataSize      int               `json:"extradata_size,omitempty"`
+	ExtradataHash      string            `json:"extradata_hash,omitempty"`
+	Logs               []LogEntry        `json:"logs,omitempty"`
+}
+
+// UnmarshalJSON decodes the stream, treating the "N/A" ffprobe prints for unset timestamps when probed with
+// WithShowOptionalFields("always") as 0.
+func (s *Stream) UnmarshalJSON(b []byte) error {
+	type Alias Stream
+	aux := &struct {
+		*Alias
+		StartPts   optionalInt `json:"start_pts"`
+		DurationTs optionalInt `json:"duration_ts"`
+	}{
+		Alias:      (*Alias)(s),
+		StartPts:   optionalInt(s.StartPts),
+		DurationTs: optionalInt(s.DurationTs),
+	}
+
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+	s.StartPts = int(aux.StartPts)
+	s.DurationTs = uint64(aux.DurationTs)
+	return nil
+}
+
+// UnmarshalJSON decodes the format, treating the "N/A" ffprobe prints for an unknown start time or duration when
+// probed with WithShowOptionalFields("always") as 0.
+func (f *Format) UnmarshalJSON(b []byte) error {
+	type Alias Format
+	aux := &struct {
+		*Alias
+		StartTimeSeconds optionalFloat `json:"start_time"`
+		DurationSeconds  optionalFloat `json:"duration"`
+	}{
+		Alias:            (*Alias)(f),
+		StartTimeSeconds: optionalFloat(f.StartTimeSeconds),
+		DurationSeconds:  optionalFloat(f.DurationSeconds),
+	}
+
+	if err := json.Unmarshal(b, aux); err != nil {
+		return err
+	}
+	f.StartTimeSeconds = float64(aux.StartTimeSeconds)
+	f.DurationSeconds = float64(aux.DurationSeconds)
+	return nil
+}
+
+// optionalInt is an integer in the ffprobe output, which decodes "N/A" as 0
+type optionalInt int64
+
+func (i *optionalInt) UnmarshalJSON(b []byte) error {
+	if string(b) == `"N/A"` {
+		*i = 0
+		return nil
+	}
+	return json.Unmarshal(b, (*int64)(i))
+}
+
+// optionalFloat is a float in the ffprobe output, which ffprobe prints as a string. It decodes "N/A" as 0.
+type optionalFloat float64
+
+func (f *optionalFloat) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return json.Unmarshal(b, (*float64)(f))
+	}
+	if str == "N/A" {
+		*f = 0
+		return nil
+	}
+	val, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return err
+	}
+	*f = optionalFloat(val)
+	return nil
+}
+
+// LogEntry is a json data structure to represent a decoder log message, see WithShowLog
+type LogEntry struct {
+	Context        string `json:"context"`
+	Level          int    `json:"level"`
+	Category       int    `json:"category"`
+	ParentContext  string `json:"parent_context"`
+	ParentCategory int    `json:"parent_category"`
+	Message        string `json:"message"`
 }
 
 // StreamDisposition is a json data structure to represent stream dispositions
@@ -104,26 +250,59 @@ type StreamDisposition struct {
 	AttachedPic     int `json:"attached_pic"`
 }
 
+// StreamGroup is a json data structure to represent stream groups, which ffprobe 7.0 and newer reports for formats
+// that group streams, like IAMF immersive audio. They are only present when probed with WithShowStreamGroups.
+type StreamGroup struct {
+	Index       int               `json:"index"`
+	ID          string            `json:"id"`
+	NbStreams   int               `json:"nb_streams"`
+	Type        string            `json:"type"`
+	Disposition StreamDisposition `json:"disposition"`
+	TagList     Tags              `json:"tags"`
+	// Components holds the type specific description of the group, like the IAMF audio element or mix presentation
+	Components []map[string]interface{} `json:"components,omitempty"`
+	Streams    []*Stream                `json:"streams"`
+}
+
 // Chapters is a json data structure to represent chapters.
 type Chapter struct {
 	ID               int     `json:"id"`
 	TimeBase         string  `json:"time_base"`
+	Start            int64   `json:"start"`
 	StartTimeSeconds float64 `json:"start_time,string"`
+	End              int64   `json:"end"`
 	EndTimeSeconds   float64 `json:"end_time,string"`
 	TagList          Tags    `json:"tags"`
 }
 
-// StartTime returns the start time of the chapter as a time.Duration
+// StartTime returns the start time of the chapter as a time.Duration. It is calculated from the integer start and
+// time_base when known, which does not suffer from the rounding of the start time in seconds.
 func (c *Chapter) StartTime() time.Duration {
-	return time.Duration(c.StartTimeSeconds * float64(time.Second))
+	return c.exactTime(c.Start, c.StartTimeSeconds)
 }
 
-// EndTime returns the end timestamp of the chapter as a time.Duration
+// EndTime returns the end timestamp of the chapter as a time.Duration. It is calculated from the integer end and
+// time_base when known, which does not suffer from the rounding of the end time in seconds.
 func (c *Chapter) EndTime() time.Duration {
-	return time.Duration(c.EndTimeSeconds * float64(time.Second))
+	return c.exactTime(c.End, c.EndTimeSeconds)
 }
 
-// Name returns the value of the "title" tag of the chapter
+// exactTime returns the time of the timestamp in the time base of the chapter, or of the seconds without time base
+func (c *Chapter) exactTime(ts int64, seconds float64) time.Duration {
+	if c.TimeBase != "" {
+		if duration, err := PTSToDuration(ts, c.TimeBase); err == nil {
+			return duration
+		}
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// Duration returns the duration of the chapter as a time.Duration
+func (c *Chapter) Duration() time.Duration {
+	return c.EndTime() - c.StartTime()
+}
+
+// Title returns the value of the "title" tag of the chapter, other chapter tags can be read from the TagList
 func (c *Chapter) Title() string {
 	title, _ := c.TagList.GetString("title")
 	return title
@@ -139,6 +318,586 @@ func (f *Format) Duration() (duration time.Duration) {
 	return time.Duration(f.DurationSeconds * float64(time.Second))
 }
 
+// Type returns the type of the stream derived from its codec_type, so it can be compared with the StreamType
+// constants. Codec types without a constant, like "unknown", are returned as is.
+func (s *Stream) Type() StreamType {
+	return StreamType(s.CodecType)
+}
+
+// EstimatedDurationFromPackets estimates the duration of a constant frame rate stream by multiplying the number of
+// read packets with the duration of a single packet derived from the frame rate. The packets are only counted when
+// the probe is done with the WithCountPackets option, ErrFieldNotFound is returned when either value is missing.
+func (s *Stream) EstimatedDurationFromPackets() (time.Duration, error) {
+	if s.NbReadPackets == "" {
+		return 0, fmt.Errorf("nb_read_packets: %w", ErrFieldNotFound)
+	}
+	packets, err := strconv.ParseInt(s.NbReadPackets, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("nb_read_packets parsing error (%v): %w", s.NbReadPackets, err)
+	}
+
+	frameRate := s.AvgFrameRate
+	if num, _, err := parseRational(frameRate); err != nil || num == 0 {
+		frameRate = s.RFrameRate
+	}
+	num, den, err := parseRational(frameRate)
+	if err != nil || num == 0 {
+		return 0, fmt.Errorf("frame rate: %w", ErrFieldNotFound)
+	}
+
+	// Split into whole seconds and a remainder to prevent overflowing on large packet counts
+	ticks := packets * den
+	seconds := ticks / num
+	remainder := ticks % num
+	return time.Duration(seconds)*time.Second + time.Duration(remainder*int64(time.Second)/num), nil
+}
+
+// IsAttachedPic returns whether the stream is an attached picture, such as the cover art of an audio file
+func (s *Stream) IsAttachedPic() bool {
+	return s.Disposition.AttachedPic == 1
+}
+
+// stillImageCodecs are the codecs of video streams that usually contain a single image
+var stillImageCodecs = []string{"png", "mjpeg", "bmp", "tiff", "webp", "jpeg2000", "jpegls", "gif", "heif", "jpegxl"}
+
+// IsStillImage returns whether the stream is a still image instead of moving video. This is the case for attached
+// pictures, for streams with a single frame, such as AVIF images which probe as single frame AV1 video streams, and for
+// streams of image codecs like PNG or JPEG that don't report multiple frames and last no longer than a single frame.
+// The duration check keeps motion JPEG in Matroska, which never reports the number of frames, and animated GIFs from
+// being considered still images.
+func (s *Stream) IsStillImage() bool {
+	if s.CodecType != string(StreamVideo) {
+		return false
+	}
+	if s.IsAttachedPic() || s.NbFrames == "1" {
+		return true
+	}
+	if !containsString(stillImageCodecs, s.CodecName) || (s.NbFrames != "" && s.NbFrames != "0" && s.NbFrames != "N/A") {
+		return false
+	}
+
+	duration := s.duration()
+	if duration <= 0 {
+		return true
+	}
+	// The image2 demuxer gives a single image the duration of one frame at its frame rate, 25 fps by default.
+	// Allow for the rounding of the duration to microseconds.
+	num, den, err := parseRational(s.RFrameRate)
+	return err == nil && num > 0 && duration < time.Duration(den*int64(time.Second)/num)*3/2
+}
+
+// IsEncrypted returns whether the stream is encrypted, which is the case when it has encryption side data or the
+// codec tag of an encrypted MP4 track ("encv", "enca", "enct" or "encs").
+func (s *Stream) IsEncrypted() bool {
+	switch s.CodecTagString {
+	case "encv", "enca", "enct", "encs":
+		return true
+	}
+	_, initInfo := s.SideDataList.findSideDataByName(SideDataTypeEncryptionInitInfo)
+	_, info := s.SideDataList.findSideDataByName(SideDataTypeEncryptionInfo)
+	return initInfo || info
+}
+
+// pixFmtBitDepths lists the bit depth of pixel formats that don't follow the naming convention of ending in the bit
+// depth after the planar "p", e.g. "yuv420p10le". The names are without endianness suffix.
+var pixFmtBitDepths = map[string]int{
+	"monow": 1, "monob": 1,
+	"rgb444": 4, "bgr444": 4, "rgb555": 5, "bgr555": 5, "rgb565": 6, "bgr565": 6,
+	"rgb48": 16, "bgr48": 16, "rgba64": 16, "bgra64": 16, "ya16": 16,
+	"p010": 10, "p012": 12, "p016": 16, "p210": 10, "p212": 12, "p216": 16, "p410": 10, "p412": 12, "p416": 16,
+	"nv20": 10, "y210": 10, "y212": 12, "xv30": 10, "xv36": 12, "v30x": 10,
+	"x2rgb10": 10, "x2bgr10": 10, "xyz12": 12,
+}
+
+// PixFmtBitDepth returns the bit depth per component of the pixel format of the stream, e.g. 10 for "yuv420p10le" and
+// 8 for "yuv420p". Unlike BitsPerRawSample, this is available for most video streams. Zero is returned when the
+// stream has no pixel format.
+func (s *Stream) PixFmtBitDepth() int {
+	name := s.PixFmt
+	if name == "" {
+		return 0
+	}
+	if strings.HasSuffix(name, "le") || strings.HasSuffix(name, "be") {
+		name = name[:len(name)-2]
+	}
+	if depth, found := pixFmtBitDepths[name]; found {
+		return depth
+	}
+
+	digits := strings.TrimRightFunc(name, func(r rune) bool {
+		return r >= '0' && r <= '9'
+	})
+	if digits == name {
+		return 8
+	}
+	// Only a number following a planar "p", a float "f" or a gray format is the bit depth, for other formats like
+	// "rgb24" the number is the amount of bits per pixel.
+	if strings.HasSuffix(digits, "p") || strings.HasSuffix(digits, "f") ||
+		strings.HasSuffix(digits, "gray") || strings.HasSuffix(digits, "ya") {
+		depth, err := strconv.Atoi(name[len(digits):])
+		if err == nil {
+			return depth
+		}
+	}
+	return 8
+}
+
+// HasClosedCaptions returns whether the video stream contains embedded CEA-608/708 closed captions
+func (s *Stream) HasClosedCaptions() bool {
+	return s.ClosedCaptions == 1
+}
+
+// IsDualMono returns whether the audio stream holds two independent mono channels instead of stereo, which ffprobe
+// reports with the "JP Dual-Mono" side data of ISDB dual mono AAC, or as a channel layout with the same channel twice,
+// like "2 channels (FC+FC)". Note that ffmpeg reports some dual mono sources, such as AC-3 in 1+1 mode, with a stereo
+// layout, those cannot be detected. The service the audio provides, e.g. commentary, can be found with
+// SideDataList.GetAudioServiceType. ErrFieldNotFound is returned when the channel layout of a two channel stream
+// is unknown.
+func (s *Stream) IsDualMono() (bool, error) {
+	if s.CodecType != string(StreamAudio) {
+		return false, nil
+	}
+	if _, found := s.SideDataList.findSideDataByName(SideDataTypeJPDualMono); found {
+		return true, nil
+	}
+	if s.Channels != 2 {
+		return false, nil
+	}
+	layout := s.ChannelLayout
+	if layout == "" {
+		return false, fmt.Errorf("channel_layout: %w", ErrFieldNotFound)
+	}
+	if start := strings.IndexByte(layout, '('); start >= 0 && strings.HasSuffix(layout, ")") {
+		layout = layout[start+1 : len(layout)-1]
+	}
+	channels := strings.Split(layout, "+")
+	return len(channels) == 2 && channels[0] == channels[1], nil
+}
+
+// IsAmbisonic returns whether the audio stream holds Ambisonics spatial audio, as used for VR and 360 degree video.
+// ffprobe reports these with a channel layout like "ambisonic 1", which it derives from the MP4 spatial audio (SA3D)
+// box or the Opus channel mapping family. Versions of ffprobe before 5.1 do not report Ambisonic channel layouts.
+func (s *Stream) IsAmbisonic() bool {
+	return s.CodecType == string(StreamAudio) && strings.HasPrefix(s.ChannelLayout, "ambisonic")
+}
+
+// Rotation returns the rotation of the stream in degrees as reported by ffprobe. The rotation of the display matrix
+// side data is used when present, otherwise the value of the "rotate" tag is returned.
+func (s *Stream) Rotation() int {
+	displayMatrix, err := s.SideDataList.GetDisplayMatrix()
+	if err == nil {
+		return displayMatrix.Rotation
+	}
+	rotate, _ := s.TagList.GetInt("rotate")
+	return int(rotate)
+}
+
+// RotationClockwise returns the rotation to apply to the stream for correct display, in degrees clockwise: 0, 90, 180
+// or 270. The rotation of the display matrix side data is counterclockwise while the "rotate" tag is clockwise,
+// this method converts both to the same convention. Rotations are rounded to the nearest multiple of 90 degrees.
+func (s *Stream) RotationClockwise() int {
+	var rotation float64
+	displayMatrix, err := s.SideDataList.GetDisplayMatrix()
+	if err == nil {
+		rotation = -float64(displayMatrix.Rotation)
+	} else {
+		rotate, _ := s.TagList.GetInt("rotate")
+		rotation = float64(rotate)
+	}
+
+	quarterTurns := int(math.Round(rotation/90)) % 4
+	if quarterTurns < 0 {
+		quarterTurns += 4
+	}
+	return quarterTurns * 90
+}
+
+// DisplayDimensions returns the width and height of the stream as it should be displayed, which means that width
+// and height are swapped when the stream is rotated by 90 or 270 degrees.
+func (s *Stream) DisplayDimensions() (width, height int) {
+	rotation := s.Rotation() % 180
+	if rotation == 90 || rotation == -90 {
+		return s.Height, s.Width
+	}
+	return s.Width, s.Height
+}
+
+// ExactStartTime returns the start time of the stream calculated from the integer start_pts and time_base, which
+// does not suffer from the rounding of the start_time in seconds. ErrFieldNotFound is returned when the stream has
+// no start time.
+func (s *Stream) ExactStartTime() (time.Duration, error) {
+	if s.StartTime == "" || s.StartTime == "N/A" {
+		return 0, fmt.Errorf("start_pts: %w", ErrFieldNotFound)
+	}
+	return PTSToDuration(int64(s.StartPts), s.TimeBase)
+}
+
+// ExactDuration returns the duration of the stream calculated from the integer duration_ts and time_base, which
+// does not suffer from the rounding of the duration in seconds. ErrFieldNotFound is returned when the stream has
+// no duration.
+func (s *Stream) ExactDuration() (time.Duration, error) {
+	if s.Duration == "" || s.Duration == "N/A" {
+		return 0, fmt.Errorf("duration_ts: %w", ErrFieldNotFound)
+	}
+	return PTSToDuration(int64(s.DurationTs), s.TimeBase)
+}
+
+// ThumbnailSize returns the dimensions of a thumbnail of the stream with the given width, with a height that keeps the
+// proportions of the stream as it is displayed. This takes both the sample aspect ratio of anamorphic video and the
+// rotation of the stream into account. Zero dimensions are returned when the stream dimensions are unknown.
+func (s *Stream) ThumbnailSize(targetWidth int) (width, height int) {
+	if s.Width <= 0 || s.Height <= 0 {
+		return 0, 0
+	}
+
+	displayWidth, displayHeight := float64(s.Width), float64(s.Height)
+	num, den, err := parseRational(strings.Replace(s.SampleAspectRatio, ":", "/", 1))
+	if err == nil && num > 0 {
+		displayWidth = displayWidth * float64(num) / float64(den)
+	}
+
+	rotation := s.Rotation() % 180
+	if rotation == 90 || rotation == -90 {
+		displayWidth, displayHeight = displayHeight, displayWidth
+	}
+
+	return targetWidth, int(math.Round(float64(targetWidth) * displayHeight / displayWidth))
+}
+
+// TagDuration returns the duration stored in the "DURATION" tag of the stream, as written to Matroska files by
+// mkvmerge and ffmpeg, e.g. "00:05:31.200000000". The fractional seconds are optional. ErrTagNotFound is returned when
+// the stream has no such tag.
+func (s *Stream) TagDuration() (time.Duration, error) {
+	value, err := s.TagList.getStringWithSuffix("DURATION")
+	if err != nil {
+		return 0, err
+	}
+	return parseTimestamp(value)
+}
+
+// constantBitRateTolerance is the relative difference allowed between the reported and the measured bit rate of a
+// constant bit rate stream, to account for container overhead and rounding.
+const constantBitRateTolerance = 0.02
+
+// IsConstantBitRate returns whether the stream has a constant bit rate. PCM audio is always constant and lossless
+// audio codecs are always variable. For other codecs, the bit rate reported in the stream header is compared to the
+// average bit rate measured by the muxer, which is only available in the statistics tags mkvmerge writes to Matroska
+// files: the "NUMBER_OF_BYTES" tag together with the stream duration, or else the "BPS" tag. Other containers, like
+// MP4, have no such measurement, and a Matroska stream often reports no bit rate of its own. ErrFieldNotFound is
+// returned in these cases, so for most files that are not muxed by mkvmerge this cannot be determined.
+func (s *Stream) IsConstantBitRate() (bool, error) {
+	switch {
+	case strings.HasPrefix(s.CodecName, "pcm_"):
+		return true, nil
+	case s.CodecName == "flac", s.CodecName == "alac", s.CodecName == "truehd", s.CodecName == "wavpack":
+		return false, nil
+	}
+
+	if s.BitRate == "" || s.BitRate == "N/A" {
+		return false, fmt.Errorf("bit_rate: %w", ErrFieldNotFound)
+	}
+	bitRate, err := valToInt64(s.BitRate)
+	if err != nil {
+		return false, err
+	}
+	if bitRate <= 0 {
+		return false, fmt.Errorf("bit_rate: %w", ErrFieldNotFound)
+	}
+	measured, err := s.measuredBitRate()
+	if err != nil {
+		return false, err
+	}
+	return math.Abs(measured-float64(bitRate))/float64(bitRate) <= constantBitRateTolerance, nil
+}
+
+// measuredBitRate returns the average bit rate of the stream from the Matroska statistics tags, see IsConstantBitRate
+func (s *Stream) measuredBitRate() (float64, error) {
+	numberOfBytes, err := s.TagList.getIntWithSuffix("NUMBER_OF_BYTES")
+	if err != nil && !errors.Is(err, ErrTagNotFound) {
+		return 0, err
+	}
+	if duration := s.duration(); err == nil && duration > 0 {
+		return float64(numberOfBytes*8) / duration.Seconds(), nil
+	}
+
+	bps, err := s.TagList.getIntWithSuffix("BPS")
+	if errors.Is(err, ErrTagNotFound) {
+		return 0, fmt.Errorf("NUMBER_OF_BYTES: %w", ErrFieldNotFound)
+	} else if err != nil {
+		return 0, err
+	}
+	return float64(bps), nil
+}
+
+// variableBitRateCodecs are lossy codecs that are encoded with a variable bit rate, see BitRateMode.
+var variableBitRateCodecs = []string{"vorbis", "opus"}
+
+// BitRateMode returns "CBR" for constant or "VBR" for variable bit rate streams as determined by IsConstantBitRate,
+// or "unknown" when that cannot be determined. Vorbis and Opus audio, which are practically always variable bit rate,
+// are reported as "VBR" without the statistics tags IsConstantBitRate needs. Video streams are usually variable bit
+// rate, but are only reported as "VBR" when their statistics show it.
+func (s *Stream) BitRateMode() string {
+	cbr, err := s.IsConstantBitRate()
+	switch {
+	case err == nil && cbr:
+		return "CBR"
+	case err == nil, containsString(variableBitRateCodecs, s.CodecName):
+		return "VBR"
+	default:
+		return "unknown"
+	}
+}
+
+// CameraMake returns the make of the camera or phone that recorded the stream from the QuickTime or Android metadata
+// tags, see Format.CameraMake. An empty string is returned when it is unknown.
+func (s *Stream) CameraMake() string {
+	return s.TagList.getFirstString(cameraMakeTags...)
+}
+
+// CameraModel returns the model of the camera or phone that recorded the stream from the QuickTime or Android
+// metadata tags, see Format.CameraModel. An empty string is returned when it is unknown.
+func (s *Stream) CameraModel() string {
+	return s.TagList.getFirstString(cameraModelTags...)
+}
+
+// imageSubtitleCodecs are the subtitle codecs that store bitmaps, textSubtitleCodecs those that store text
+var (
+	imageSubtitleCodecs = []string{"dvd_subtitle", "dvb_subtitle", "hdmv_pgs_subtitle", "xsub"}
+	textSubtitleCodecs  = []string{
+		"subrip", "srt", "ass", "ssa", "mov_text", "webvtt", "text", "ttml", "microdvd", "subviewer", "subviewer1",
+		"sami", "realtext", "jacosub", "mpl2", "pjs", "vplayer", "stl", "eia_608",
+	}
+)
+
+// IsImageSubtitle returns whether the stream holds bitmap subtitles, like DVD, DVB or Blu-ray PGS subtitles. These
+// need OCR to be converted to text.
+func (s *Stream) IsImageSubtitle() bool {
+	return s.CodecType == string(StreamSubtitle) && containsString(imageSubtitleCodecs, s.CodecName)
+}
+
+// IsTextSubtitle returns whether the stream holds text subtitles, like SubRip, ASS or MP4 timed text. These can be
+// converted to other text formats such as WebVTT directly. Note that subtitles with an unknown codec are neither
+// image nor text subtitles.
+func (s *Stream) IsTextSubtitle() bool {
+	return s.CodecType == string(StreamSubtitle) && containsString(textSubtitleCodecs, s.CodecName)
+}
+
+// HasExtradata returns whether the stream has codec extradata in the container, like the SPS and PPS of H.264 in
+// the avcC box. Streams without it, such as Annex B H.264 with in-band parameter sets, cannot be packaged as
+// fragmented MP4 directly. Note that ffprobe only reports the extradata size since version 5.0.
+func (s *Stream) HasExtradata() bool {
+	return s.ExtradataSize > 0
+}
+
+// HandlerName returns the value of the "handler_name" tag of MOV and MP4 tracks, which describes the purpose of the
+// track, e.g. "VideoHandler", "SoundHandler" or "GoPro MET". An empty string is returned when it is not tagged.
+func (s *Stream) HandlerName() string {
+	handlerName, _ := s.TagList.GetString("handler_name")
+	return handlerName
+}
+
+// telemetryCodecTags are the codec tags of camera telemetry tracks: GoPro GPMF, DJI metadata and Google CAMM
+var telemetryCodecTags = []string{"gpmd", "djmd", "dbgi", "camm"}
+
+// telemetryHandlerNames are the handler names of camera telemetry tracks
+var telemetryHandlerNames = []string{"GoPro MET", "DJI meta", "CameraMetadataMotionHandler"}
+
+// IsTelemetry returns whether the stream is a camera telemetry track, like the GPS and sensor data of GoPro and DJI
+// cameras. These are detected by their codec tag or handler name.
+func (s *Stream) IsTelemetry() bool {
+	if s.CodecType != string(StreamData) {
+		return false
+	}
+	return containsString(telemetryCodecTags, s.CodecTagString) ||
+		containsString(telemetryHandlerNames, strings.TrimSpace(s.HandlerName()))
+}
+
+// duration returns the duration of the stream, falling back to the Matroska "DURATION" tag, or zero when it is unknown
+func (s *Stream) duration() time.Duration {
+	duration, _ := parseSeconds(s.Duration)
+	if duration <= 0 {
+		duration, _ = s.TagDuration()
+	}
+	return duration
+}
+
+// cameraMakeTags and cameraModelTags are the tags used by cameras and phones to store the device that made a recording
+var (
+	cameraMakeTags  = []string{"com.apple.quicktime.make", "com.android.manufacturer", "make"}
+	cameraModelTags = []string{"com.apple.quicktime.model", "com.android.model", "model"}
+)
+
+// CameraMake returns the make of the camera or phone that recorded the media file, e.g. "Apple", from the QuickTime
+// or Android metadata tags. An empty string is returned when it is unknown.
+func (f *Format) CameraMake() string {
+	return f.TagList.getFirstString(cameraMakeTags...)
+}
+
+// CameraModel returns the model of the camera or phone that recorded the media file, e.g. "iPhone 12", from the
+// QuickTime or Android metadata tags. An empty string is returned when it is unknown.
+func (f *Format) CameraModel() string {
+	return f.TagList.getFirstString(cameraModelTags...)
+}
+
+// Title returns the title of the media file from the "title" tag, or the "TITLE" Vorbis comment
+func (f *Format) Title() string {
+	return f.TagList.getFirstString("title", "TITLE")
+}
+
+// Artist returns the artist of the media file from the "artist" tag, or the "ARTIST" Vorbis comment
+func (f *Format) Artist() string {
+	return f.TagList.getFirstString("artist", "ARTIST")
+}
+
+// Album returns the album of the media file from the "album" tag, or the "ALBUM" Vorbis comment
+func (f *Format) Album() string {
+	return f.TagList.getFirstString("album", "ALBUM")
+}
+
+// AlbumArtist returns the album artist of the media file from the "album_artist" tag, or its Vorbis comment variants
+func (f *Format) AlbumArtist() string {
+	return f.TagList.getFirstString("album_artist", "ALBUMARTIST", "ALBUM ARTIST", "ALBUM_ARTIST")
+}
+
+// Genre returns the genre of the media file from the "genre" tag, or the "GENRE" Vorbis comment
+func (f *Format) Genre() string {
+	return f.TagList.getFirstString("genre", "GENRE")
+}
+
+// yearTags are the tags holding the release date or year of the media file, in order of preference
+var yearTags = []string{"date", "DATE", "year", "YEAR", "TYER", "TDRC"}
+
+// Year returns the release year of the media file from the first four digits of the "date" tag, or of one of its
+// ID3 and Vorbis comment variants, which can hold a full date like "2019-05-03". ErrTagNotFound is returned when
+// none of the tags is present.
+func (f *Format) Year() (int, error) {
+	date := strings.TrimSpace(f.TagList.getFirstString(yearTags...))
+	if date == "" {
+		return 0, ErrTagNotFound
+	}
+	if len(date) > 4 {
+		date = date[:4]
+	}
+	year, err := strconv.Atoi(date)
+	if err != nil || len(date) != 4 {
+		return 0, fmt.Errorf("year parsing error (%v): %w", date, strconv.ErrSyntax)
+	}
+	return year, nil
+}
+
+// SizeInt returns the size of the media file in bytes, ErrFieldNotFound is returned when the size is unknown.
+func (f *Format) SizeInt() (int64, error) {
+	if f.Size == "" || f.Size == "N/A" {
+		return 0, fmt.Errorf("size: %w", ErrFieldNotFound)
+	}
+	return valToInt64(f.Size)
+}
+
+// BitRateInt returns the bit rate of the stream in bits per second. When ffprobe does not report the bit rate, as is
+// common for Matroska files, the value of the "BPS" statistics tag is used instead. ErrFieldNotFound is returned
+// when neither is available.
+func (s *Stream) BitRateInt() (int64, error) {
+	if s.BitRate != "" && s.BitRate != "N/A" {
+		return valToInt64(s.BitRate)
+	}
+	bitRate, err := s.TagList.getIntWithSuffix("BPS")
+	if errors.Is(err, ErrTagNotFound) {
+		return 0, fmt.Errorf("bit_rate: %w", ErrFieldNotFound)
+	}
+	return bitRate, err
+}
+
+// BitRatePerChannel returns the bit rate of the audio stream divided by its number of channels, which allows
+// comparing the quality of streams with different channel layouts. The bit rate is determined as by BitRateInt,
+// ErrFieldNotFound is returned when the bit rate or the channel count is unknown.
+func (s *Stream) BitRatePerChannel() (int64, error) {
+	bitRate, err := s.BitRateInt()
+	if err != nil {
+		return 0, err
+	}
+	if s.Channels <= 0 {
+		return 0, fmt.Errorf("channels: %w", ErrFieldNotFound)
+	}
+	return bitRate / int64(s.Channels), nil
+}
+
+// SampleRateInt returns the sample rate of the audio stream in Hz. Zero is returned when the sample rate is not
+// reported, as for streams other than audio.
+func (s *Stream) SampleRateInt() (int, error) {
+	if s.SampleRate == "" {
+		return 0, nil
+	}
+	sampleRate, err := strconv.Atoi(s.SampleRate)
+	if err != nil {
+		return 0, fmt.Errorf("sample_rate parsing error (%v): %w", s.SampleRate, err)
+	}
+	return sampleRate, nil
+}
+
+// NumberOfFrames returns the number of frames in the stream. When ffprobe does not report nb_frames, as is common for
+// Matroska files, the value of the "NUMBER_OF_FRAMES" statistics tag is used instead. ErrFieldNotFound is returned
+// when neither is available.
+func (s *Stream) NumberOfFrames() (int64, error) {
+	if s.NbFrames != "" && s.NbFrames != "N/A" {
+		return valToInt64(s.NbFrames)
+	}
+	frames, err := s.TagList.getIntWithSuffix("NUMBER_OF_FRAMES")
+	if errors.Is(err, ErrTagNotFound) {
+		return 0, fmt.Errorf("nb_frames: %w", ErrFieldNotFound)
+	}
+	return frames, err
+}
+
+// SubtitleCueCount returns the number of cues of a subtitle stream, as every subtitle frame is a single cue. It returns
+// NumberOfFrames, or the number of read packets when the frames are unknown and the packets were counted with
+// WithCountPackets. ErrFieldNotFound is returned for other streams, or when neither count is available.
+func (s *Stream) SubtitleCueCount() (int, error) {
+	if s.CodecType != string(StreamSubtitle) {
+		return 0, fmt.Errorf("subtitle nb_frames: %w", ErrFieldNotFound)
+	}
+	frames, err := s.NumberOfFrames()
+	if errors.Is(err, ErrFieldNotFound) && s.NbReadPackets != "" && s.NbReadPackets != "N/A" {
+		frames, err = valToInt64(s.NbReadPackets)
+	}
+	return int(frames), err
+}
+
+// AudioSampleRatesConsistent returns whether all audio streams have the same sample rate. Streams of which the sample
+// rate is unknown are ignored.
+func (p *ProbeData) AudioSampleRatesConsistent() bool {
+	first := 0
+	for _, s := range p.Streams {
+		if s == nil || s.CodecType != string(StreamAudio) {
+			continue
+		}
+		sampleRate, err := s.SampleRateInt()
+		if err != nil || sampleRate <= 0 {
+			continue
+		}
+		if first == 0 {
+			first = sampleRate
+		} else if sampleRate != first {
+			return false
+		}
+	}
+	return true
+}
+
+// ChaptersSorted returns the chapters sorted by their start time, as ffprobe does not guarantee their order.
+// Chapters with the same start time keep their original order.
+func (p *ProbeData) ChaptersSorted() []Chapter {
+	chapters := make([]Chapter, 0, len(p.Chapters))
+	for _, c := range p.Chapters {
+		if c != nil {
+			chapters = append(chapters, *c)
+		}
+	}
+	sort.SliceStable(chapters, func(i, j int) bool {
+		return chapters[i].StartTimeSeconds < chapters[j].StartTimeSeconds
+	})
+	return chapters
+}
+
 // StreamType returns all streams which are of the given type
 func (p *ProbeData) StreamType(streamType StreamType) (streams []Stream) {
 	for _, s := range p.Streams {
@@ -157,11 +916,75 @@ func (p *ProbeData) StreamType(streamType StreamType) (streams []Stream) {
 	return streams
 }
 
-// FirstVideoStream returns the first video stream found
+// FirstVideoStream returns the first video stream found. When there are multiple video streams, the first one with the
+// default disposition is preferred, and attached pictures such as cover art are only returned when there is no other
+// video stream.
 func (p *ProbeData) FirstVideoStream() *Stream {
+	var first *Stream
+	for _, s := range p.Streams {
+		if s == nil || s.CodecType != string(StreamVideo) || s.IsAttachedPic() {
+			continue
+		}
+		if s.Disposition.Default == 1 {
+			return s
+		}
+		if first == nil {
+			first = s
+		}
+	}
+	if first != nil {
+		return first
+	}
 	return p.firstStream(StreamVideo)
 }
 
+// VideoCodecAliases maps video codec names to the canonical names returned by NormalizeVideoCodec. It can be changed
+// to adjust the naming used by ProbeData.PrimaryVideoCodec.
+var VideoCodecAliases = map[string]string{
+	"avc":        "h264",
+	"avc1":       "h264",
+	"h265":       "hevc",
+	"hvc1":       "hevc",
+	"hev1":       "hevc",
+	"mpeg1video": "mpeg1",
+	"mpeg2video": "mpeg2",
+	"vp08":       "vp8",
+	"vp09":       "vp9",
+	"av01":       "av1",
+}
+
+// NormalizeVideoCodec returns the canonical name of the video codec with the given name, as defined by
+// VideoCodecAliases. The name is compared case insensitively, names without alias are returned in lower case.
+func NormalizeVideoCodec(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if canonical, ok := VideoCodecAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// PrimaryVideoCodec returns the normalized codec name of the first video stream as chosen by FirstVideoStream, see
+// NormalizeVideoCodec. An empty string is returned when there is no video stream.
+func (p *ProbeData) PrimaryVideoCodec() string {
+	s := p.FirstVideoStream()
+	if s == nil {
+		return ""
+	}
+	return NormalizeVideoCodec(s.CodecName)
+}
+
+// HasMultipleVideoStreams returns whether there is more than one video stream, such as multiple angles.
+// Attached pictures are not counted.
+func (p *ProbeData) HasMultipleVideoStreams() bool {
+	count := 0
+	for _, s := range p.Streams {
+		if s != nil && s.CodecType == string(StreamVideo) && !s.IsAttachedPic() {
+			count++
+		}
+	}
+	return count > 1
+}
+
 // FirstAudioStream returns the first audio stream found
 func (p *ProbeData) FirstAudioStream() *Stream {
 	return p.firstStream(StreamAudio)
@@ -193,3 +1016,500 @@ func (p *ProbeData) firstStream(streamType StreamType) *Stream {
 	}
 	return nil
 }
+
+// WebCompatibleVideoCodecs and WebCompatibleAudioCodecs are the codecs browsers can play in MP4 files, and
+// WebMCompatibleVideoCodecs and WebMCompatibleAudioCodecs those in WebM files. They make up the baseline used by
+// ProbeData.IsWebCompatible, and can be changed to tune which files are considered playable by browsers.
+var (
+	WebCompatibleVideoCodecs  = []string{"h264", "vp9"}
+	WebCompatibleAudioCodecs  = []string{"aac", "opus"}
+	WebMCompatibleVideoCodecs = []string{"vp8", "vp9", "av1"}
+	WebMCompatibleAudioCodecs = []string{"opus", "vorbis"}
+)
+
+// IsWebCompatible returns whether the media file can be played by browsers directly. The codecs of all video and audio
+// streams of MP4 files are checked against WebCompatibleVideoCodecs and WebCompatibleAudioCodecs, and those of WebM
+// files against WebMCompatibleVideoCodecs and WebMCompatibleAudioCodecs. Other types of streams are ignored.
+// As ffprobe reports the same format for MOV and MP4, and for Matroska and WebM files, QuickTime files are recognized
+// by their "qt" major brand and Matroska files are only compatible when their codecs are allowed in WebM.
+func (p *ProbeData) IsWebCompatible() bool {
+	if p.Format == nil {
+		return false
+	}
+
+	var videoCodecs, audioCodecs []string
+	formatNames := strings.Split(p.Format.FormatName, ",")
+	majorBrand, _ := p.Format.TagList.GetString("major_brand")
+	switch {
+	case containsString(formatNames, "mp4") && strings.TrimSpace(majorBrand) != "qt":
+		videoCodecs, audioCodecs = WebCompatibleVideoCodecs, WebCompatibleAudioCodecs
+	case containsString(formatNames, "webm"):
+		videoCodecs, audioCodecs = WebMCompatibleVideoCodecs, WebMCompatibleAudioCodecs
+	default:
+		return false
+	}
+
+	mediaStreams := 0
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		switch s.CodecType {
+		case string(StreamVideo):
+			if !containsString(videoCodecs, s.CodecName) {
+				return false
+			}
+		case string(StreamAudio):
+			if !containsString(audioCodecs, s.CodecName) {
+				return false
+			}
+		default:
+			continue
+		}
+		mediaStreams++
+	}
+	return mediaStreams > 0
+}
+
+// MP4CompatibleVideoCodecs, MP4CompatibleAudioCodecs and MP4CompatibleSubtitleCodecs are the codecs used by
+// ProbeData.CanRemuxToMP4. They can be changed to tune which streams are considered storable in MP4 as is.
+var (
+	MP4CompatibleVideoCodecs    = []string{"h264", "hevc", "av1"}
+	MP4CompatibleAudioCodecs    = []string{"aac", "ac3", "eac3", "mp3"}
+	MP4CompatibleSubtitleCodecs = []string{"mov_text"}
+)
+
+// CanRemuxToMP4 returns whether all streams of the media file can be copied into an MP4 container without
+// transcoding. It checks the codecs of all video, audio and subtitle streams against MP4CompatibleVideoCodecs,
+// MP4CompatibleAudioCodecs and MP4CompatibleSubtitleCodecs. Data streams are ignored, attachments such as fonts
+// cannot be stored in MP4 and make it return false.
+func (p *ProbeData) CanRemuxToMP4() bool {
+	mediaStreams := 0
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		var codecs []string
+		switch s.CodecType {
+		case string(StreamVideo):
+			codecs = MP4CompatibleVideoCodecs
+		case string(StreamAudio):
+			codecs = MP4CompatibleAudioCodecs
+		case string(StreamSubtitle):
+			codecs = MP4CompatibleSubtitleCodecs
+		case string(StreamAttachment):
+			return false
+		default:
+			continue
+		}
+		if !containsString(codecs, s.CodecName) {
+			return false
+		}
+		mediaStreams++
+	}
+	return mediaStreams > 0
+}
+
+func containsString(list []string, str string) bool {
+	for _, s := range list {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAudioOnly returns whether the media file has audio streams and no video streams other than attached pictures,
+// such as the cover art of a music file.
+func (p *ProbeData) IsAudioOnly() bool {
+	hasAudio := false
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		switch s.CodecType {
+		case string(StreamAudio):
+			hasAudio = true
+		case string(StreamVideo):
+			if !s.IsAttachedPic() {
+				return false
+			}
+		}
+	}
+	return hasAudio
+}
+
+// IsEncrypted returns whether any of the streams is encrypted, e.g. protected with Widevine, PlayReady or FairPlay DRM.
+// Note that ffprobe does not report the encryption scheme used.
+func (p *ProbeData) IsEncrypted() bool {
+	for _, s := range p.Streams {
+		if s != nil && s.IsEncrypted() {
+			return true
+		}
+	}
+	return false
+}
+
+// Kind classifies the media file by its streams. It is MediaVideo when there is a video stream that is not a still
+// image, MediaAudio when there are audio streams and otherwise only still images such as cover art, and MediaImage
+// when there are only still images, like single frame AV1 (AVIF) or PNG streams.
+func (p *ProbeData) Kind() MediaKind {
+	hasAudio, hasImage := false, false
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		switch s.CodecType {
+		case string(StreamVideo):
+			if !s.IsStillImage() {
+				return MediaVideo
+			}
+			hasImage = true
+		case string(StreamAudio):
+			hasAudio = true
+		}
+	}
+
+	switch {
+	case hasAudio:
+		return MediaAudio
+	case hasImage:
+		return MediaImage
+	default:
+		return MediaUnknown
+	}
+}
+
+// Location returns the geographic location where the media file was recorded, parsed from the ISO 6709 "location"
+// format tag written by phones and cameras, e.g. "+37.7749-122.4194/". Both decimal degrees and the degrees, minutes
+// and seconds notations are supported, an altitude is ignored. The ok result is false when there is no valid location.
+func (p *ProbeData) Location() (lat, lon float64, ok bool) {
+	if p.Format == nil {
+		return 0, 0, false
+	}
+	location := p.Format.TagList.getFirstString("com.apple.quicktime.location.ISO6709", "location", "location-eng")
+
+	parts := splitSignedNumbers(strings.TrimSuffix(strings.TrimSpace(location), "/"))
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	lat, err := parseISO6709Coordinate(parts[0], 2)
+	if err != nil || lat < -90 || lat > 90 {
+		return 0, 0, false
+	}
+	lon, err = parseISO6709Coordinate(parts[1], 3)
+	if err != nil || lon < -180 || lon > 180 {
+		return 0, 0, false
+	}
+	return lat, lon, true
+}
+
+// splitSignedNumbers splits a string like "+37.7749-122.4194" into the numbers with their sign
+func splitSignedNumbers(str string) []string {
+	var parts []string
+	for len(str) > 0 {
+		if str[0] != '+' && str[0] != '-' {
+			return nil
+		}
+		end := strings.IndexAny(str[1:], "+-")
+		if end < 0 {
+			parts = append(parts, str)
+			break
+		}
+		parts = append(parts, str[:end+1])
+		str = str[end+1:]
+	}
+	return parts
+}
+
+// parseISO6709Coordinate parses a signed ISO 6709 coordinate, which has degreeDigits digits for the degrees,
+// optionally followed by two digits for the minutes and two digits for the seconds, and an optional fraction.
+func parseISO6709Coordinate(str string, degreeDigits int) (float64, error) {
+	sign := 1.0
+	if str[0] == '-' {
+		sign = -1
+	}
+	str = str[1:]
+
+	intDigits := strings.IndexByte(str, '.')
+	if intDigits < 0 {
+		intDigits = len(str)
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("coordinate parsing error (%v): %w", str, err)
+	}
+
+	switch intDigits {
+	case degreeDigits:
+		// Decimal degrees
+	case degreeDigits + 2:
+		// Degrees and decimal minutes
+		degrees := math.Floor(value / 100)
+		value = degrees + (value-degrees*100)/60
+	case degreeDigits + 4:
+		// Degrees, minutes and decimal seconds
+		degrees := math.Floor(value / 10000)
+		minutes := math.Floor((value - degrees*10000) / 100)
+		value = degrees + minutes/60 + (value-degrees*10000-minutes*100)/3600
+	default:
+		return 0, fmt.Errorf("invalid coordinate: %q", str)
+	}
+	return sign * value, nil
+}
+
+// LongestStreamDuration returns the duration of the longest stream, or zero when no stream duration is known. Stream
+// durations fall back to the Matroska "DURATION" tag, see Stream.TagDuration.
+// Compared to the container duration returned by Format.Duration, a big difference can indicate a muxing problem.
+func (p *ProbeData) LongestStreamDuration() time.Duration {
+	var longest time.Duration
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		if duration := s.duration(); duration > longest {
+			longest = duration
+		}
+	}
+	return longest
+}
+
+// DurationsAligned returns whether the durations of the first video stream and the first audio stream differ at most
+// tolerance. Stream durations fall back to the Matroska "DURATION" tag, see Stream.TagDuration. True is returned when
+// there is no video or audio stream, or when either duration is unknown, as there is nothing to compare.
+func (p *ProbeData) DurationsAligned(tolerance time.Duration) bool {
+	video, audio := p.FirstVideoStream(), p.FirstAudioStream()
+	if video == nil || audio == nil {
+		return true
+	}
+	videoDuration, audioDuration := video.duration(), audio.duration()
+	if videoDuration <= 0 || audioDuration <= 0 {
+		return true
+	}
+	delta := videoDuration - audioDuration
+	if delta < 0 {
+		delta = -delta
+	}
+	return delta <= tolerance
+}
+
+// SyncReport returns the start time of every stream by stream index, which shows the offsets between the streams
+// for A/V sync validation. A stream starting later than another has a higher start time, start times can be negative.
+// Streams without a known start time are left out.
+func (p *ProbeData) SyncReport() map[int]time.Duration {
+	report := make(map[int]time.Duration, len(p.Streams))
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		startTime, err := parseSeconds(s.StartTime)
+		if err != nil {
+			continue
+		}
+		report[s.Index] = startTime
+	}
+	return report
+}
+
+// MuxOverhead estimates the overhead of the container as a fraction of the file size, e.g. 0.02 for 2%. It is
+// calculated by subtracting the size of the audio and video streams, derived from their bit rates and the duration,
+// from the file size. An error is returned when the file size, duration or any audio or video bit rate is unknown.
+func (p *ProbeData) MuxOverhead() (float64, error) {
+	if p.Format == nil {
+		return 0, fmt.Errorf("format: %w", ErrFieldNotFound)
+	}
+	size, err := p.Format.SizeInt()
+	if err != nil {
+		return 0, err
+	}
+	if size <= 0 || p.Format.DurationSeconds <= 0 {
+		return 0, fmt.Errorf("size and duration: %w", ErrFieldNotFound)
+	}
+
+	var streamBitRate int64
+	for _, s := range p.Streams {
+		if s == nil || (s.CodecType != string(StreamVideo) && s.CodecType != string(StreamAudio)) || s.IsAttachedPic() {
+			continue
+		}
+		bitRate, err := s.BitRateInt()
+		if err != nil {
+			return 0, fmt.Errorf("stream %d: %w", s.Index, err)
+		}
+		streamBitRate += bitRate
+	}
+
+	sizeBits := float64(size) * 8
+	return (sizeBits - float64(streamBitRate)*p.Format.DurationSeconds) / sizeBits, nil
+}
+
+// truncatedOverhead is the negative mux overhead below which LooksTruncated considers a file truncated, as the streams
+// would need more data than the file holds. A small margin allows for inaccurate stream bit rates.
+const truncatedOverhead = -0.05
+
+// indexedFormats are the formats LooksTruncated expects a duration from, as they store an index of their contents
+var indexedFormats = []string{"mp4", "matroska"}
+
+// LooksTruncated returns whether the media file appears to be truncated or incomplete, e.g. by an interrupted
+// download. This is a heuristic, a file is considered truncated when:
+//   - it is an MP4, MOV or Matroska file with audio or video, but the container reports no duration, as happens when
+//     the MP4 moov atom is missing. Other formats, like live streams and raw elementary streams, may have no duration.
+//   - it was probed by a local path and the file on disk no longer has the size ffprobe reported
+//   - the bit rates of its streams over the duration need clearly more data than the file holds, see MuxOverhead
+func (p *ProbeData) LooksTruncated() bool {
+	if p.Format == nil {
+		return false
+	}
+	indexed := false
+	for _, name := range strings.Split(p.Format.FormatName, ",") {
+		indexed = indexed || containsString(indexedFormats, name)
+	}
+	kind := p.Kind()
+	if indexed && (kind == MediaVideo || kind == MediaAudio) && p.Format.DurationSeconds <= 0 {
+		return true
+	}
+
+	size, err := p.Format.SizeInt()
+	if err == nil && size > 0 && isLocalPath(p.Format.Filename) {
+		if info, err := os.Stat(p.Format.Filename); err == nil && info.Size() != size {
+			return true
+		}
+	}
+
+	overhead, err := p.MuxOverhead()
+	return err == nil && overhead < truncatedOverhead
+}
+
+// isLocalPath returns whether the input filename ffprobe reports is a path on the local file system. Inputs using a
+// protocol, like "pipe:", "fd:" or URLs, and stdin are not.
+func isLocalPath(filename string) bool {
+	return filename != "" && filename != "-" && inputScheme(filename) == "file" && !strings.HasPrefix(filename, "file:")
+}
+
+// IsPortrait returns whether the first video stream is displayed in portrait orientation, meaning it is higher than
+// it is wide after applying its rotation.
+func (p *ProbeData) IsPortrait() bool {
+	videoStream := p.FirstVideoStream()
+	if videoStream == nil {
+		return false
+	}
+	width, height := videoStream.DisplayDimensions()
+	return height > width
+}
+
+// fragmentedMP4Brands are the ISO BMFF brands signalling a fragmented MP4 file, as used for DASH and CMAF.
+var fragmentedMP4Brands = []string{"dash", "msdh", "msix", "cmfc", "cmf2", "cmfs", "cmff", "cmfl"}
+
+// IsFragmentedMP4 returns whether the media file is a fragmented MP4 (fMP4). This is determined by looking
+// for a DASH or CMAF brand in the major_brand and compatible_brands format tags.
+func (p *ProbeData) IsFragmentedMP4() bool {
+	if p.Format == nil || !strings.Contains(p.Format.FormatName, "mp4") {
+		return false
+	}
+
+	majorBrand, _ := p.Format.TagList.GetString("major_brand")
+	brandList := append([]string{strings.TrimSpace(majorBrand)}, p.Format.CompatibleBrands()...)
+	for _, brand := range brandList {
+		if containsString(fragmentedMP4Brands, brand) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompatibleBrands returns the ISO BMFF brands from the compatible_brands format tag. The tag value is a
+// concatenation of 4 character brands, e.g. "isomiso2avc1mp41", which is split into the separate brands.
+// Brands shorter than 4 characters are padded with spaces, these are trimmed from the returned brands.
+func (f *Format) CompatibleBrands() []string {
+	brands, err := f.TagList.GetString("compatible_brands")
+	if err != nil {
+		return nil
+	}
+
+	list := make([]string, 0, len(brands)/4)
+	for len(brands) >= 4 {
+		list = append(list, strings.TrimSpace(brands[:4]))
+		brands = brands[4:]
+	}
+	return list
+}
+
+// PTSToDuration converts a timestamp (e.g. a PTS or DTS) in the given time base, like "1/90000", to a time.Duration.
+// An error is returned when the time base is malformed.
+func PTSToDuration(pts int64, timeBase string) (time.Duration, error) {
+	num, den, err := parseRational(timeBase)
+	if err != nil {
+		return 0, err
+	}
+	if num <= 0 || den < 0 {
+		return 0, fmt.Errorf("invalid time base: %q", timeBase)
+	}
+
+	// Split into whole seconds and a remainder to prevent overflowing on large timestamps
+	ticks := pts * num
+	seconds := ticks / den
+	remainder := ticks % den
+	return time.Duration(seconds)*time.Second + time.Duration(remainder*int64(time.Second)/den), nil
+}
+
+// parseTimestamp parses a timestamp in the HH:MM:SS.nnnnnnnnn format, the fractional seconds are optional
+func parseTimestamp(str string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(str), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid timestamp: %q", str)
+	}
+	hours, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("timestamp hours parsing error (%v): %w", str, err)
+	}
+	minutes, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("invalid timestamp minutes: %q", str)
+	}
+
+	secondParts := strings.SplitN(parts[2], ".", 2)
+	seconds, err := strconv.ParseUint(secondParts[0], 10, 8)
+	if err != nil || seconds >= 60 {
+		return 0, fmt.Errorf("invalid timestamp seconds: %q", str)
+	}
+	var nanoseconds uint64
+	if len(secondParts) == 2 {
+		// Pad or truncate the fraction to nanosecond precision
+		fraction := (secondParts[1] + "000000000")[:9]
+		nanoseconds, err = strconv.ParseUint(fraction, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("timestamp fraction parsing error (%v): %w", str, err)
+		}
+	}
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute +
+		time.Duration(seconds)*time.Second + time.Duration(nanoseconds), nil
+}
+
+// parseSeconds parses a number of seconds as used by ffprobe for timestamps, e.g. "5.312000".
+func parseSeconds(str string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("seconds parsing error (%v): %w", str, err)
+	}
+	return time.Duration(math.Round(seconds * float64(time.Second))), nil
+}
+
+// parseRational parses a rational number as used by ffprobe for frame rates and time bases, e.g. "30000/1001".
+func parseRational(str string) (num, den int64, err error) {
+	parts := strings.SplitN(str, "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid rational number: %q", str)
+	}
+	num, err = strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("rational numerator parsing error (%v): %w", str, err)
+	}
+	den, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("rational denominator parsing error (%v): %w", str, err)
+	}
+	if den == 0 {
+		return 0, 0, fmt.Errorf("invalid rational number with zero denominator: %q", str)
+	}
+	return num, den, nil
+}
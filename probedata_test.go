@@ -0,0 +1,1430 @@
+package ffprobe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_StreamTypeString(t *testing.T) {
+	if StreamVideo.String() != "video" || StreamAttachment.String() != "attachment" {
+		t.Errorf("Unexpected stream type names %s and %s", StreamVideo, StreamAttachment)
+	}
+	if s := fmt.Sprint(StreamAny); s != "any" {
+		t.Errorf("Expected StreamAny to print as any, got %s", s)
+	}
+}
+
+func Test_ParseStreamType(t *testing.T) {
+	for _, streamType := range []StreamType{StreamAny, StreamVideo, StreamAudio, StreamSubtitle, StreamData, StreamAttachment} {
+		if parsed, ok := ParseStreamType(streamType.String()); !ok || parsed != streamType {
+			t.Errorf("Expected %s to parse to itself, got %s (%v)", streamType, parsed, ok)
+		}
+	}
+	if parsed, ok := ParseStreamType("Audio"); !ok || parsed != StreamAudio {
+		t.Errorf("Expected Audio to parse case insensitively, got %s (%v)", parsed, ok)
+	}
+	if _, ok := ParseStreamType("unknown"); ok {
+		t.Errorf("Expected unknown stream type not to parse")
+	}
+	if _, ok := ParseStreamType(""); ok {
+		t.Errorf("Expected an empty stream type not to parse")
+	}
+}
+
+func Test_StreamTypeOfStream(t *testing.T) {
+	stream := &Stream{CodecType: "subtitle"}
+	if stream.Type() != StreamSubtitle {
+		t.Errorf("Expected subtitle stream type, got %s", stream.Type())
+	}
+}
+
+func Test_IsFragmentedMP4(t *testing.T) {
+	data := &ProbeData{
+		Format: &Format{
+			FormatName: "mov,mp4,m4a,3gp,3g2,mj2",
+			TagList: Tags{
+				"major_brand":       "iso6",
+				"compatible_brands": "iso6cmfcdashmp41",
+			},
+		},
+	}
+	if !data.IsFragmentedMP4() {
+		t.Errorf("Expected file with cmfc brand to be fragmented")
+	}
+
+	data.Format.TagList["compatible_brands"] = "isomiso2avc1mp41"
+	if data.IsFragmentedMP4() {
+		t.Errorf("Expected file without fragmented brands to not be fragmented")
+	}
+
+	data.Format.TagList["major_brand"] = "dash"
+	if !data.IsFragmentedMP4() {
+		t.Errorf("Expected file with dash major brand to be fragmented")
+	}
+
+	data.Format.FormatName = "matroska,webm"
+	if data.IsFragmentedMP4() {
+		t.Errorf("Expected non MP4 file to not be fragmented")
+	}
+}
+
+func Test_IsFastStart(t *testing.T) {
+	data := &ProbeData{Format: &Format{Filename: "assets/test_faststart.mp4", FormatName: "mov,mp4,m4a,3gp,3g2,mj2"}}
+	if !data.IsFastStart() {
+		t.Errorf("Expected %s to be fast start", data.Format.Filename)
+	}
+
+	data.Format.Filename = "assets/test.mp4"
+	if data.IsFastStart() {
+		t.Errorf("Expected %s with the moov atom at the end not to be fast start", data.Format.Filename)
+	}
+
+	data.Format.Filename = "pipe:"
+	if data.IsFastStart() {
+		t.Errorf("Expected a probe of stdin not to be fast start")
+	}
+}
+
+func Test_CompatibleBrands(t *testing.T) {
+	format := &Format{
+		TagList: Tags{
+			"compatible_brands": "isomiso2avc1mp41",
+		},
+	}
+	brands := format.CompatibleBrands()
+	expected := []string{"isom", "iso2", "avc1", "mp41"}
+	if len(brands) != len(expected) {
+		t.Fatalf("Expected %d brands, got %v", len(expected), brands)
+	}
+	for i := range expected {
+		if brands[i] != expected[i] {
+			t.Errorf("Expected brand %d to be %s, got %s", i, expected[i], brands[i])
+		}
+	}
+
+	format.TagList = nil
+	if brands := format.CompatibleBrands(); len(brands) != 0 {
+		t.Errorf("Expected no brands without tag, got %v", brands)
+	}
+}
+
+func Test_EstimatedDurationFromPackets(t *testing.T) {
+	stream := &Stream{
+		AvgFrameRate:  "25/1",
+		NbReadPackets: "250",
+	}
+	duration, err := stream.EstimatedDurationFromPackets()
+	if err != nil {
+		t.Fatalf("Error estimating duration: %v", err)
+	}
+	if duration != 10*time.Second {
+		t.Errorf("Expected duration of 10s, got %v", duration)
+	}
+
+	stream.AvgFrameRate = "0/0"
+	stream.RFrameRate = "30000/1001"
+	duration, err = stream.EstimatedDurationFromPackets()
+	if err != nil {
+		t.Fatalf("Error estimating duration: %v", err)
+	}
+	if duration != 8341666666 {
+		t.Errorf("Expected duration of 8.341666666s, got %v", duration)
+	}
+
+	// A large packet count with a large frame rate denominator must not overflow
+	stream.AvgFrameRate = "1564875/52216"
+	stream.NbReadPackets = "1000000"
+	duration, err = stream.EstimatedDurationFromPackets()
+	if err != nil {
+		t.Fatalf("Error estimating duration: %v", err)
+	}
+	if duration != 33367521367521 {
+		t.Errorf("Expected duration of 9h16m7.521367521s, got %v", duration)
+	}
+
+	stream.NbReadPackets = ""
+	if _, err = stream.EstimatedDurationFromPackets(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without packet count, got %v", err)
+	}
+}
+
+func Test_IsWebCompatible(t *testing.T) {
+	data := &ProbeData{
+		Format: &Format{FormatName: "mov,mp4,m4a,3gp,3g2,mj2"},
+		Streams: []*Stream{
+			{CodecType: "video", CodecName: "h264"},
+			{CodecType: "audio", CodecName: "aac"},
+			{CodecType: "data", CodecName: "bin_data"},
+		},
+	}
+	if !data.IsWebCompatible() {
+		t.Errorf("Expected h264/aac in mp4 to be web compatible")
+	}
+
+	data.Streams[1].CodecName = "ac3"
+	if data.IsWebCompatible() {
+		t.Errorf("Expected ac3 audio to not be web compatible")
+	}
+
+	data.Streams[1].CodecName = "aac"
+	data.Format.FormatName = "avi"
+	if data.IsWebCompatible() {
+		t.Errorf("Expected avi container to not be web compatible")
+	}
+
+	data.Format = &Format{FormatName: "mov,mp4,m4a,3gp,3g2,mj2", TagList: Tags{"major_brand": "qt  "}}
+	if data.IsWebCompatible() {
+		t.Errorf("Expected h264/aac in mov to not be web compatible")
+	}
+
+	data.Format = &Format{FormatName: "matroska,webm"}
+	if data.IsWebCompatible() {
+		t.Errorf("Expected h264/aac in mkv to not be web compatible")
+	}
+
+	data.Streams[0].CodecName, data.Streams[1].CodecName = "vp9", "opus"
+	if !data.IsWebCompatible() {
+		t.Errorf("Expected vp9/opus in webm to be web compatible")
+	}
+}
+
+func Test_CanRemuxToMP4(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "hevc"},
+			{Index: 1, CodecType: "audio", CodecName: "eac3"},
+			{Index: 2, CodecType: "subtitle", CodecName: "mov_text"},
+			{Index: 3, CodecType: "data", CodecName: "bin_data"},
+		},
+	}
+	if !data.CanRemuxToMP4() {
+		t.Errorf("Expected hevc with eac3 to be remuxable to MP4")
+	}
+
+	data.Streams[2].CodecName = "subrip"
+	if data.CanRemuxToMP4() {
+		t.Errorf("Expected subrip subtitles not to be remuxable to MP4")
+	}
+
+	data.Streams = []*Stream{{CodecType: "attachment", CodecName: "ttf"}}
+	if data.CanRemuxToMP4() {
+		t.Errorf("Expected font attachments not to be remuxable to MP4")
+	}
+}
+
+func Test_Diff(t *testing.T) {
+	src := &ProbeData{
+		Format: &Format{DurationSeconds: 10},
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "mpeg2video", Width: 1920, Height: 1080},
+			{Index: 1, CodecType: "audio", CodecName: "ac3"},
+			{Index: 2, CodecType: "subtitle", CodecName: "dvd_subtitle"},
+		},
+	}
+	dst := &ProbeData{
+		Format: &Format{DurationSeconds: 10.04},
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264", Width: 1920, Height: 1080},
+			{Index: 1, CodecType: "audio", CodecName: "ac3"},
+		},
+	}
+
+	diff := Diff(src, dst)
+	if diff.DurationDelta != 40*time.Millisecond {
+		t.Errorf("Expected a duration delta of 40ms, got %v", diff.DurationDelta)
+	}
+	if diff.ResolutionChanged() {
+		t.Errorf("Expected the resolution to be unchanged")
+	}
+	if len(diff.CodecChanges) != 1 || diff.CodecChanges[0] != (CodecChange{StreamVideo, 0, "mpeg2video", "h264"}) {
+		t.Errorf("Unexpected codec changes %+v", diff.CodecChanges)
+	}
+	if len(diff.StreamCountChanges) != 1 || diff.StreamCountChanges[StreamSubtitle] != -1 {
+		t.Errorf("Unexpected stream count changes %v", diff.StreamCountChanges)
+	}
+	if diff.Preserved(100 * time.Millisecond) {
+		t.Errorf("Expected the dropped subtitle stream not to be preserved")
+	}
+
+	dst.Streams = append(dst.Streams, &Stream{Index: 2, CodecType: "subtitle", CodecName: "dvd_subtitle"})
+	if !Diff(src, dst).Preserved(100 * time.Millisecond) {
+		t.Errorf("Expected the transcode to be preserved")
+	}
+}
+
+func Test_BitRatePerChannel(t *testing.T) {
+	stream := &Stream{CodecType: "audio", Channels: 6, TagList: Tags{"BPS-eng": "640000"}}
+	if bitRate, err := stream.BitRatePerChannel(); err != nil || bitRate != 106666 {
+		t.Errorf("Expected 106666 bps per channel, got %d (%v)", bitRate, err)
+	}
+
+	stream.Channels = 0
+	if _, err := stream.BitRatePerChannel(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without channels, got %v", err)
+	}
+
+	stream = &Stream{CodecType: "audio", Channels: 2}
+	if _, err := stream.BitRatePerChannel(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without bit rate, got %v", err)
+	}
+}
+
+func Test_SampleRateInt(t *testing.T) {
+	stream := &Stream{SampleRate: "48000"}
+	if sampleRate, err := stream.SampleRateInt(); err != nil || sampleRate != 48000 {
+		t.Errorf("Expected sample rate 48000, got %d (%v)", sampleRate, err)
+	}
+	stream.SampleRate = ""
+	if sampleRate, err := stream.SampleRateInt(); err != nil || sampleRate != 0 {
+		t.Errorf("Expected sample rate 0 when unknown, got %d (%v)", sampleRate, err)
+	}
+	stream.SampleRate = "fast"
+	if _, err := stream.SampleRateInt(); err == nil {
+		t.Errorf("Expected an error for an invalid sample rate")
+	}
+}
+
+func Test_AudioSampleRatesConsistent(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video"},
+			{Index: 1, CodecType: "audio", SampleRate: "48000"},
+			{Index: 2, CodecType: "audio"},
+			{Index: 3, CodecType: "audio", SampleRate: "48000"},
+		},
+	}
+	if !data.AudioSampleRatesConsistent() {
+		t.Errorf("Expected consistent sample rates")
+	}
+
+	data.Streams[2].SampleRate = "44100"
+	if data.AudioSampleRatesConsistent() {
+		t.Errorf("Expected inconsistent sample rates")
+	}
+}
+
+func Test_SelectBestAudio(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264"},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 2, TagList: Tags{"language": "eng"}},
+			{Index: 2, CodecType: "audio", CodecName: "ac3", Channels: 6, TagList: Tags{"language": "eng"}},
+			{Index: 3, CodecType: "audio", CodecName: "eac3", Channels: 6, TagList: Tags{"language": "eng"}},
+			{Index: 4, CodecType: "audio", CodecName: "truehd", Channels: 8, TagList: Tags{"language": "fre"}},
+			{Index: 5, CodecType: "audio", CodecName: "eac3", Channels: 6, TagList: Tags{"language": "eng"}},
+		},
+	}
+
+	if best := data.SelectBestAudio([]string{"ENG"}); best == nil || best.Index != 3 {
+		t.Errorf("Expected stream 3 to be the best english audio, got %+v", best)
+	}
+	if best := data.SelectBestAudio([]string{"fre", "eng"}); best == nil || best.Index != 4 {
+		t.Errorf("Expected stream 4 to be the best french audio, got %+v", best)
+	}
+	if best := data.SelectBestAudio(nil); best == nil || best.Index != 4 {
+		t.Errorf("Expected stream 4 to be the best audio without preference, got %+v", best)
+	}
+
+	data.Streams = data.Streams[:1]
+	if best := data.SelectBestAudio([]string{"eng"}); best != nil {
+		t.Errorf("Expected no audio stream, got %+v", best)
+	}
+}
+
+func Test_AudioLanguages(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", TagList: Tags{"language": "dut"}},
+			{Index: 1, CodecType: "audio", TagList: Tags{"language": "eng"}},
+			{Index: 2, CodecType: "audio", TagList: Tags{"language": "FRE"}},
+			{Index: 3, CodecType: "audio", TagList: Tags{"language": "und"}},
+			{Index: 4, CodecType: "audio"},
+			{Index: 5, CodecType: "audio", TagList: Tags{"language": "Eng"}},
+		},
+	}
+
+	langs := data.AudioLanguages()
+	if len(langs) != 2 || langs[0] != "eng" || langs[1] != "fre" {
+		t.Errorf("Expected languages [eng fre], got %v", langs)
+	}
+}
+
+func Test_TrackSummary(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264", Width: 1920, Height: 1080, Channels: 2,
+				Disposition: StreamDisposition{Default: 1}},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 6, Width: 10, TagList: Tags{"language": "eng"}},
+			nil,
+			{Index: 2, CodecType: "subtitle", CodecName: "subrip", TagList: Tags{"language": "dut"},
+				Disposition: StreamDisposition{Forced: 1}},
+		},
+	}
+
+	expected := []TrackInfo{
+		{Index: 0, Type: StreamVideo, Codec: "h264", Width: 1920, Height: 1080, Default: true},
+		{Index: 1, Type: StreamAudio, Codec: "aac", Language: "eng", Channels: 6},
+		{Index: 2, Type: StreamSubtitle, Codec: "subrip", Language: "dut", Forced: true},
+	}
+	tracks := data.TrackSummary()
+	if len(tracks) != len(expected) {
+		t.Fatalf("Expected %d tracks, got %+v", len(expected), tracks)
+	}
+	for i := range expected {
+		if tracks[i] != expected[i] {
+			t.Errorf("Expected track %+v, got %+v", expected[i], tracks[i])
+		}
+	}
+}
+
+func Test_SelectBestSubtitle(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264"},
+			{Index: 1, CodecType: "subtitle", CodecName: "subrip", TagList: Tags{"language": "eng"}},
+			{Index: 2, CodecType: "subtitle", CodecName: "subrip", TagList: Tags{"language": "eng"},
+				Disposition: StreamDisposition{Forced: 1}},
+			{Index: 3, CodecType: "subtitle", CodecName: "subrip", TagList: Tags{"language": "dut"},
+				Disposition: StreamDisposition{Default: 1}},
+		},
+	}
+
+	if best := data.SelectBestSubtitle([]string{"eng"}, false); best == nil || best.Index != 1 {
+		t.Errorf("Expected stream 1 to be the best english subtitle, got %+v", best)
+	}
+	if best := data.SelectBestSubtitle([]string{"eng"}, true); best == nil || best.Index != 2 {
+		t.Errorf("Expected stream 2 to be the best forced english subtitle, got %+v", best)
+	}
+	if best := data.SelectBestSubtitle(nil, false); best == nil || best.Index != 3 {
+		t.Errorf("Expected default stream 3 to be the best subtitle without preference, got %+v", best)
+	}
+
+	data.Streams[2].Disposition.Forced = 0
+	if best := data.SelectBestSubtitle([]string{"eng"}, true); best != nil {
+		t.Errorf("Expected no forced subtitle stream, got %+v", best)
+	}
+}
+
+func Test_IsPortrait(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{CodecType: "video", Width: 1920, Height: 1080},
+		},
+	}
+	if data.IsPortrait() {
+		t.Errorf("Expected landscape video to not be portrait")
+	}
+
+	data.Streams[0].SideDataList = SideDataList{
+		{SideDataBase: SideDataBase{Type: SideDataTypeDisplayMatrix}, Data: &SideDataDisplayMatrix{Rotation: -90}},
+	}
+	if !data.IsPortrait() {
+		t.Errorf("Expected landscape video rotated by -90 degrees to be portrait")
+	}
+
+	data.Streams[0].SideDataList = nil
+	data.Streams[0].TagList = Tags{"rotate": "270"}
+	if !data.IsPortrait() {
+		t.Errorf("Expected landscape video with rotate tag of 270 to be portrait")
+	}
+}
+
+func Test_PTSToDuration(t *testing.T) {
+	duration, err := PTSToDuration(135000, "1/90000")
+	if err != nil {
+		t.Fatalf("Error converting PTS: %v", err)
+	}
+	if duration != 1500*time.Millisecond {
+		t.Errorf("Expected 1.5s, got %v", duration)
+	}
+
+	// A timestamp close to wrapping around in a 90kHz time base
+	duration, err = PTSToDuration(1<<33, "1/90000")
+	if err != nil {
+		t.Fatalf("Error converting PTS: %v", err)
+	}
+	if duration != 95443717688888 {
+		t.Errorf("Expected 95443.717688888s, got %v", duration)
+	}
+
+	for _, timeBase := range []string{"", "1/0", "abc", "0/1"} {
+		if _, err = PTSToDuration(1, timeBase); err == nil {
+			t.Errorf("Expected error for time base %q", timeBase)
+		}
+	}
+}
+
+func Test_MultipleVideoStreams(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "mjpeg", Disposition: StreamDisposition{AttachedPic: 1}},
+			{Index: 1, CodecType: "video", CodecName: "h264"},
+			{Index: 2, CodecType: "audio", CodecName: "aac"},
+		},
+	}
+	if data.HasMultipleVideoStreams() {
+		t.Errorf("Expected attached picture to not count as video stream")
+	}
+	if video := data.FirstVideoStream(); video == nil || video.Index != 1 {
+		t.Errorf("Expected stream 1 to be the first video stream, got %+v", video)
+	}
+
+	data.Streams = append(data.Streams, &Stream{Index: 3, CodecType: "video", Disposition: StreamDisposition{Default: 1}})
+	if !data.HasMultipleVideoStreams() {
+		t.Errorf("Expected multiple video streams")
+	}
+	if video := data.FirstVideoStream(); video == nil || video.Index != 3 {
+		t.Errorf("Expected default stream 3 to be the first video stream, got %+v", video)
+	}
+
+	data.Streams = data.Streams[:1]
+	if video := data.FirstVideoStream(); video == nil || video.Index != 0 {
+		t.Errorf("Expected attached picture to be returned without other video streams, got %+v", video)
+	}
+}
+
+func Test_PrimaryVideoCodec(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "mjpeg", Disposition: StreamDisposition{AttachedPic: 1}},
+			{Index: 1, CodecType: "video", CodecName: "mpeg2video"},
+		},
+	}
+	if codec := data.PrimaryVideoCodec(); codec != "mpeg2" {
+		t.Errorf("Expected mpeg2, got %s", codec)
+	}
+	if NormalizeVideoCodec("H265") != "hevc" || NormalizeVideoCodec("h264") != "h264" {
+		t.Errorf("Unexpected normalized codecs %s and %s", NormalizeVideoCodec("H265"), NormalizeVideoCodec("h264"))
+	}
+
+	data.Streams = nil
+	if codec := data.PrimaryVideoCodec(); codec != "" {
+		t.Errorf("Expected no codec without video, got %s", codec)
+	}
+}
+
+func Test_LongestStreamDuration(t *testing.T) {
+	data := &ProbeData{
+		Format: &Format{DurationSeconds: 10},
+		Streams: []*Stream{
+			{CodecType: "video", Duration: "9.960000"},
+			{CodecType: "audio", Duration: "10.005333"},
+			{CodecType: "data", Duration: "N/A"},
+		},
+	}
+	if duration := data.LongestStreamDuration(); duration != 10005333*time.Microsecond {
+		t.Errorf("Expected longest stream duration of 10.005333s, got %v", duration)
+	}
+}
+
+func Test_StreamTags(t *testing.T) {
+	var tags StreamTags
+	tags.setFrom(Tags{
+		"title":                "Commentary",
+		"handler_name":         "SoundHandler",
+		"BPS-eng":              "128000",
+		"DURATION-eng":         "00:05:31.200000000",
+		"NUMBER_OF_FRAMES-eng": "7946",
+	})
+	if tags.Title != "Commentary" || tags.HandlerName != "SoundHandler" {
+		t.Errorf("Unexpected title or handler name: %+v", tags)
+	}
+	if tags.BPS != 128000 {
+		t.Errorf("Expected BPS of 128000, got %d", tags.BPS)
+	}
+	if tags.Duration != "00:05:31.200000000" {
+		t.Errorf("Unexpected duration: %s", tags.Duration)
+	}
+	if tags.NumberOfFrames != 7946 {
+		t.Errorf("Expected 7946 frames, got %d", tags.NumberOfFrames)
+	}
+}
+
+func Test_NumberOfFrames(t *testing.T) {
+	stream := &Stream{NbFrames: "120", TagList: Tags{"NUMBER_OF_FRAMES": "100"}}
+	if frames, err := stream.NumberOfFrames(); err != nil || frames != 120 {
+		t.Errorf("Expected 120 frames from nb_frames, got %d (%v)", frames, err)
+	}
+
+	stream = &Stream{TagList: Tags{"NUMBER_OF_FRAMES-eng": "7946"}}
+	if frames, err := stream.NumberOfFrames(); err != nil || frames != 7946 {
+		t.Errorf("Expected 7946 frames from the tag, got %d (%v)", frames, err)
+	}
+
+	stream = &Stream{}
+	if _, err := stream.NumberOfFrames(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound, got %v", err)
+	}
+}
+
+func Test_SubtitleCueCount(t *testing.T) {
+	stream := &Stream{CodecType: "subtitle", CodecName: "subrip", TagList: Tags{"NUMBER_OF_FRAMES-eng": "1432"}}
+	if cues, err := stream.SubtitleCueCount(); err != nil || cues != 1432 {
+		t.Errorf("Expected 1432 cues from the tag, got %d (%v)", cues, err)
+	}
+
+	stream = &Stream{CodecType: "subtitle", CodecName: "mov_text", NbFrames: "N/A", NbReadPackets: "87"}
+	if cues, err := stream.SubtitleCueCount(); err != nil || cues != 87 {
+		t.Errorf("Expected 87 cues from the read packets, got %d (%v)", cues, err)
+	}
+
+	stream = &Stream{CodecType: "subtitle", CodecName: "subrip"}
+	if _, err := stream.SubtitleCueCount(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without counts, got %v", err)
+	}
+
+	stream = &Stream{CodecType: "video", CodecName: "h264", NbFrames: "120"}
+	if _, err := stream.SubtitleCueCount(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound for a video stream, got %v", err)
+	}
+}
+
+func Test_DurationsAligned(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", Duration: "60.000000"},
+			{Index: 1, CodecType: "audio", TagList: Tags{"DURATION-eng": "00:00:59.980000000"}},
+		},
+	}
+	if !data.DurationsAligned(50 * time.Millisecond) {
+		t.Errorf("Expected durations within 50ms to be aligned")
+	}
+
+	data.Streams[1].TagList = Tags{"DURATION-eng": "00:00:57.000000000"}
+	if data.DurationsAligned(time.Second) {
+		t.Errorf("Expected audio 3s shorter than video not to be aligned")
+	}
+	if data.LongestStreamDuration() != time.Minute {
+		t.Errorf("Expected longest stream duration of 1m, got %v", data.LongestStreamDuration())
+	}
+}
+
+func Test_SyncReport(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", StartTime: "0.040000"},
+			{Index: 1, CodecType: "audio", StartTime: "-0.021333"},
+			{Index: 2, CodecType: "data", StartTime: "N/A"},
+		},
+	}
+	report := data.SyncReport()
+	if len(report) != 2 {
+		t.Fatalf("Expected 2 streams in the report, got %v", report)
+	}
+	if report[0] != 40*time.Millisecond || report[1] != -21333*time.Microsecond {
+		t.Errorf("Unexpected start times: %v", report)
+	}
+}
+
+func Test_IsAudioOnly(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{CodecType: "audio", CodecName: "mp3"},
+			{CodecType: "video", CodecName: "mjpeg", Disposition: StreamDisposition{AttachedPic: 1}},
+		},
+	}
+	if !data.IsAudioOnly() {
+		t.Errorf("Expected audio with cover art to be audio only")
+	}
+
+	data.Streams = append(data.Streams, &Stream{CodecType: "video", CodecName: "h264"})
+	if data.IsAudioOnly() {
+		t.Errorf("Expected audio with a video stream to not be audio only")
+	}
+
+	data.Streams = data.Streams[1:2]
+	if data.IsAudioOnly() {
+		t.Errorf("Expected file without audio to not be audio only")
+	}
+}
+
+func Test_ExactTimes(t *testing.T) {
+	stream := &Stream{
+		TimeBase:   "1/90000",
+		StartPts:   3003,
+		StartTime:  "0.033367",
+		DurationTs: 540540000,
+		Duration:   "6006.000000",
+	}
+	startTime, err := stream.ExactStartTime()
+	if err != nil {
+		t.Fatalf("Error getting start time: %v", err)
+	}
+	if startTime != 33366666 {
+		t.Errorf("Expected start time of 33.366666ms, got %v", startTime)
+	}
+
+	duration, err := stream.ExactDuration()
+	if err != nil {
+		t.Fatalf("Error getting duration: %v", err)
+	}
+	if duration != 6006*time.Second {
+		t.Errorf("Expected duration of 6006s, got %v", duration)
+	}
+
+	stream.Duration = ""
+	if _, err = stream.ExactDuration(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without duration, got %v", err)
+	}
+}
+
+func Test_ThumbnailSize(t *testing.T) {
+	// Anamorphic widescreen PAL DVD
+	stream := &Stream{Width: 720, Height: 576, SampleAspectRatio: "64:45"}
+	if width, height := stream.ThumbnailSize(320); width != 320 || height != 180 {
+		t.Errorf("Expected 320x180 thumbnail, got %dx%d", width, height)
+	}
+
+	// Rotated phone video
+	stream = &Stream{Width: 1920, Height: 1080, SampleAspectRatio: "1:1", TagList: Tags{"rotate": "90"}}
+	if width, height := stream.ThumbnailSize(360); width != 360 || height != 640 {
+		t.Errorf("Expected 360x640 thumbnail, got %dx%d", width, height)
+	}
+
+	stream = &Stream{}
+	if width, height := stream.ThumbnailSize(360); width != 0 || height != 0 {
+		t.Errorf("Expected no thumbnail size without dimensions, got %dx%d", width, height)
+	}
+}
+
+func Test_MuxOverhead(t *testing.T) {
+	data := &ProbeData{
+		Format: &Format{Size: "1250000", DurationSeconds: 10},
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", BitRate: "872000"},
+			{Index: 1, CodecType: "audio", TagList: Tags{"BPS-eng": "128000"}},
+			{Index: 2, CodecType: "data"},
+		},
+	}
+	overhead, err := data.MuxOverhead()
+	if err != nil {
+		t.Fatalf("Error getting mux overhead: %v", err)
+	}
+	if math.Abs(overhead-0.0) > 1e-9 {
+		t.Errorf("Expected no overhead, got %f", overhead)
+	}
+
+	data.Format.Size = "1300000"
+	overhead, err = data.MuxOverhead()
+	if err != nil {
+		t.Fatalf("Error getting mux overhead: %v", err)
+	}
+	if math.Abs(overhead-50000.0/1300000) > 1e-9 {
+		t.Errorf("Expected overhead of %f, got %f", 50000.0/1300000, overhead)
+	}
+
+	data.Streams[1].TagList = nil
+	if _, err = data.MuxOverhead(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without audio bit rate, got %v", err)
+	}
+}
+
+func Test_LooksTruncated(t *testing.T) {
+	data := &ProbeData{
+		Format: &Format{Filename: "pipe:", FormatName: "mov,mp4,m4a,3gp,3g2,mj2", Size: "1300000", DurationSeconds: 10},
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264", BitRate: "872000"},
+			{Index: 1, CodecType: "audio", CodecName: "aac", BitRate: "128000"},
+		},
+	}
+	if data.LooksTruncated() {
+		t.Errorf("Expected complete file not to look truncated")
+	}
+
+	data.Format.Size = "600000"
+	if !data.LooksTruncated() {
+		t.Errorf("Expected file too small for its streams to look truncated")
+	}
+
+	data.Format.Size = "1300000"
+	data.Format.DurationSeconds = 0
+	if !data.LooksTruncated() {
+		t.Errorf("Expected file without duration to look truncated")
+	}
+
+	data.Format.FormatName = "h264"
+	if data.LooksTruncated() {
+		t.Errorf("Expected raw elementary stream without duration not to look truncated")
+	}
+	data.Format.FormatName = "mpegts"
+	if data.LooksTruncated() {
+		t.Errorf("Expected live stream without duration not to look truncated")
+	}
+
+	for _, filename := range []string{"fd:", "pipe:3", "-", "https://example.com/test.mp4"} {
+		if isLocalPath(filename) {
+			t.Errorf("Expected %s not to be a local path", filename)
+		}
+	}
+
+	file, err := ioutil.TempFile("", "go-ffprobe-truncated-*.mp4")
+	if err != nil {
+		t.Fatalf("Error creating temp file: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, _ = file.Write(make([]byte, 1000))
+	_ = file.Close()
+
+	data.Format.Filename = file.Name()
+	data.Format.DurationSeconds = 10
+	if !data.LooksTruncated() {
+		t.Errorf("Expected file with a different size on disk to look truncated")
+	}
+}
+
+func Test_IsStillImage(t *testing.T) {
+	tests := []struct {
+		stream Stream
+		still  bool
+	}{
+		{Stream{CodecType: "video", CodecName: "av1", NbFrames: "1"}, true},
+		{Stream{CodecType: "video", CodecName: "av1", NbFrames: "240"}, false},
+		{Stream{CodecType: "video", CodecName: "png"}, true},
+		{Stream{CodecType: "video", CodecName: "mjpeg", NbFrames: "1500"}, false},
+		{Stream{CodecType: "video", CodecName: "mjpeg", RFrameRate: "25/1", Duration: "0.040000"}, true},
+		{Stream{CodecType: "video", CodecName: "mjpeg", RFrameRate: "30000/1001", Duration: "0.033367"}, true},
+		{Stream{CodecType: "video", CodecName: "mjpeg", RFrameRate: "25/1",
+			TagList: Tags{"DURATION": "00:01:00.000000000"}}, false},
+		{Stream{CodecType: "video", CodecName: "gif", RFrameRate: "100/1", Duration: "2.500000"}, false},
+		{Stream{CodecType: "video", CodecName: "h264", Disposition: StreamDisposition{AttachedPic: 1}}, true},
+		{Stream{CodecType: "video", CodecName: "h264"}, false},
+		{Stream{CodecType: "audio", CodecName: "aac", NbFrames: "1"}, false},
+	}
+	for i, test := range tests {
+		if still := test.stream.IsStillImage(); still != test.still {
+			t.Errorf("Test %d: expected still image %v, got %v", i, test.still, still)
+		}
+	}
+}
+
+func Test_IsEncrypted(t *testing.T) {
+	data := &ProbeData{
+		Streams: []*Stream{
+			{CodecType: "video", CodecTagString: "avc1"},
+			{CodecType: "audio", CodecTagString: "mp4a"},
+		},
+	}
+	if data.IsEncrypted() {
+		t.Errorf("Expected unencrypted file")
+	}
+
+	data.Streams[1].SideDataList = SideDataList{
+		{SideDataBase: SideDataBase{Type: SideDataTypeEncryptionInitInfo}, Data: &SideDataUnknown{}},
+	}
+	if !data.IsEncrypted() {
+		t.Errorf("Expected file with encryption side data to be encrypted")
+	}
+
+	data.Streams[1].SideDataList = nil
+	data.Streams[0].CodecTagString = "encv"
+	if !data.IsEncrypted() {
+		t.Errorf("Expected file with encv codec tag to be encrypted")
+	}
+}
+
+func Test_Kind(t *testing.T) {
+	tests := []struct {
+		streams []*Stream
+		kind    MediaKind
+	}{
+		{[]*Stream{{CodecType: "video", CodecName: "h264"}, {CodecType: "audio", CodecName: "aac"}}, MediaVideo},
+		{[]*Stream{{CodecType: "audio", CodecName: "flac"},
+			{CodecType: "video", CodecName: "png", Disposition: StreamDisposition{AttachedPic: 1}}}, MediaAudio},
+		{[]*Stream{{CodecType: "video", CodecName: "av1", NbFrames: "1"}}, MediaImage},
+		{[]*Stream{{CodecType: "video", CodecName: "mjpeg", RFrameRate: "25/1",
+			TagList: Tags{"DURATION": "00:01:00.000000000"}}, {CodecType: "audio", CodecName: "pcm_s16le"}}, MediaVideo},
+		{[]*Stream{{CodecType: "data"}}, MediaUnknown},
+	}
+	for i, test := range tests {
+		data := &ProbeData{Streams: test.streams}
+		if kind := data.Kind(); kind != test.kind {
+			t.Errorf("Test %d: expected kind %s, got %s", i, test.kind, kind)
+		}
+	}
+}
+
+func Test_TagDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"00:05:31.200000000":  5*time.Minute + 31200*time.Millisecond,
+		"01:00:00":            time.Hour,
+		"00:00:01.5":          1500 * time.Millisecond,
+		"00:00:00.0416666667": 41666666,
+	}
+	for value, expected := range tests {
+		stream := &Stream{TagList: Tags{"DURATION-eng": value}}
+		duration, err := stream.TagDuration()
+		if err != nil {
+			t.Errorf("Error parsing %s: %v", value, err)
+		} else if duration != expected {
+			t.Errorf("Expected %s to be %v, got %v", value, expected, duration)
+		}
+	}
+
+	for _, value := range []string{"", "5:31", "00:61:00", "00:00:aa", "00:00:01.x"} {
+		stream := &Stream{TagList: Tags{"DURATION": value}}
+		if _, err := stream.TagDuration(); err == nil {
+			t.Errorf("Expected error parsing %q", value)
+		}
+	}
+
+	stream := &Stream{}
+	if _, err := stream.TagDuration(); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound without tag, got %v", err)
+	}
+}
+
+func Test_PixFmtBitDepth(t *testing.T) {
+	tests := map[string]int{
+		"yuv420p":     8,
+		"yuvj420p":    8,
+		"yuv420p10le": 10,
+		"yuv422p12be": 12,
+		"yuva444p16":  16,
+		"gbrp10le":    10,
+		"gray":        8,
+		"gray10le":    10,
+		"nv12":        8,
+		"p010le":      10,
+		"rgb24":       8,
+		"rgb48le":     16,
+		"bgra":        8,
+		"grayf32le":   32,
+		"":            0,
+	}
+	for pixFmt, expected := range tests {
+		stream := &Stream{PixFmt: pixFmt}
+		if depth := stream.PixFmtBitDepth(); depth != expected {
+			t.Errorf("Expected bit depth %d for %q, got %d", expected, pixFmt, depth)
+		}
+	}
+}
+
+func Test_Camera(t *testing.T) {
+	format := &Format{
+		TagList: Tags{
+			"com.apple.quicktime.make":  "Apple",
+			"com.apple.quicktime.model": "iPhone 12",
+		},
+	}
+	if format.CameraMake() != "Apple" || format.CameraModel() != "iPhone 12" {
+		t.Errorf("Unexpected camera %s %s", format.CameraMake(), format.CameraModel())
+	}
+
+	stream := &Stream{}
+	if stream.CameraMake() != "" || stream.CameraModel() != "" {
+		t.Errorf("Expected unknown camera without tags")
+	}
+}
+
+func Test_ReplayGain(t *testing.T) {
+	stream := &Stream{
+		TagList: Tags{
+			"REPLAYGAIN_TRACK_GAIN": "-6.50 dB",
+			"REPLAYGAIN_TRACK_PEAK": "0.988251",
+			"replaygain_album_gain": "+1.2dB",
+		},
+	}
+	info, err := stream.ReplayGain()
+	if err != nil {
+		t.Fatalf("Error getting replaygain: %v", err)
+	}
+	if info.TrackGain != -6.5 || info.TrackPeak != 0.988251 || info.AlbumGain != 1.2 || info.AlbumPeak != 0 {
+		t.Errorf("Unexpected replaygain %+v", info)
+	}
+
+	stream.TagList = Tags{"replaygain_track_gain": "loud"}
+	if _, err = stream.ReplayGain(); err == nil {
+		t.Errorf("Expected an error for an invalid gain")
+	}
+
+	stream.TagList = nil
+	if _, err = stream.ReplayGain(); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound without tags, got %v", err)
+	}
+
+	// ffprobe reports the ID3 tags of MP3 files on the format
+	data := &ProbeData{
+		Format:  &Format{FormatName: "mp3", TagList: Tags{"REPLAYGAIN_TRACK_GAIN": "-3.20 dB"}},
+		Streams: []*Stream{{CodecType: "audio", CodecName: "mp3"}},
+	}
+	info, err = data.ReplayGain()
+	if err != nil || info.TrackGain != -3.2 {
+		t.Errorf("Expected the track gain from the format tags, got %+v (%v)", info, err)
+	}
+
+	data.Streams[0].TagList = Tags{"replaygain_track_gain": "-1.00 dB"}
+	if info, err = data.ReplayGain(); err != nil || info.TrackGain != -1 {
+		t.Errorf("Expected the track gain from the stream tags, got %+v (%v)", info, err)
+	}
+
+	data = &ProbeData{Format: &Format{FormatName: "mp3"}}
+	if _, err = data.ReplayGain(); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound without tags, got %v", err)
+	}
+}
+
+func Test_ChapterTags(t *testing.T) {
+	var chapter Chapter
+	err := json.Unmarshal([]byte(`{"id":1,"start_time":"0.000000","end_time":"60.000000",`+
+		`"tags":{"title":"Opening","dvd_cell":"3"}}`), &chapter)
+	if err != nil {
+		t.Fatalf("Error decoding chapter: %v", err)
+	}
+	if chapter.Title() != "Opening" {
+		t.Errorf("Unexpected title %s", chapter.Title())
+	}
+	if cell, err := chapter.TagList.GetInt("dvd_cell"); err != nil || cell != 3 {
+		t.Errorf("Expected custom chapter tag dvd_cell 3, got %d (%v)", cell, err)
+	}
+}
+
+func Test_ChapterExactTimes(t *testing.T) {
+	var chapter Chapter
+	err := json.Unmarshal([]byte(`{"id":1,"time_base":"1/1000000000","start":2002000000,"start_time":"2.002000",`+
+		`"end":4004666667,"end_time":"4.004667"}`), &chapter)
+	if err != nil {
+		t.Fatalf("Error decoding chapter: %v", err)
+	}
+	if chapter.StartTime() != 2002*time.Millisecond {
+		t.Errorf("Expected start time of 2.002s, got %v", chapter.StartTime())
+	}
+	if chapter.EndTime() != 4004666667*time.Nanosecond {
+		t.Errorf("Expected exact end time of 4.004666667s, got %v", chapter.EndTime())
+	}
+
+	chapter = Chapter{StartTimeSeconds: 1.5, EndTimeSeconds: 3}
+	if chapter.StartTime() != 1500*time.Millisecond || chapter.EndTime() != 3*time.Second {
+		t.Errorf("Expected times from seconds without time base, got %v and %v", chapter.StartTime(), chapter.EndTime())
+	}
+}
+
+func Test_ChaptersSorted(t *testing.T) {
+	data := &ProbeData{
+		Chapters: []*Chapter{
+			{ID: 2, StartTimeSeconds: 120, EndTimeSeconds: 180},
+			{ID: 0, StartTimeSeconds: 0, EndTimeSeconds: 60.5},
+			{ID: 1, StartTimeSeconds: 60.5, EndTimeSeconds: 120},
+		},
+	}
+	chapters := data.ChaptersSorted()
+	if len(chapters) != 3 || chapters[0].ID != 0 || chapters[1].ID != 1 || chapters[2].ID != 2 {
+		t.Errorf("Expected chapters in start time order, got %+v", chapters)
+	}
+	if chapters[0].Duration() != 60500*time.Millisecond {
+		t.Errorf("Expected first chapter to last 60.5s, got %v", chapters[0].Duration())
+	}
+	if data.Chapters[0].ID != 2 {
+		t.Errorf("Expected the original chapters to be unchanged")
+	}
+}
+
+func Test_IsDualMono(t *testing.T) {
+	tests := []struct {
+		stream   Stream
+		dualMono bool
+	}{
+		{Stream{CodecType: "audio", Channels: 2, ChannelLayout: "stereo"}, false},
+		{Stream{CodecType: "audio", Channels: 2, ChannelLayout: "2 channels (FC+FC)"}, true},
+		{Stream{CodecType: "audio", Channels: 1, ChannelLayout: "mono"}, false},
+		{Stream{CodecType: "video"}, false},
+	}
+	for _, test := range tests {
+		dualMono, err := test.stream.IsDualMono()
+		if err != nil || dualMono != test.dualMono {
+			t.Errorf("Expected dual mono %v for layout %s, got %v (%v)", test.dualMono, test.stream.ChannelLayout, dualMono, err)
+		}
+	}
+
+	stream := &Stream{CodecType: "audio", Channels: 2}
+	if _, err := stream.IsDualMono(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without channel layout, got %v", err)
+	}
+
+	err := json.Unmarshal([]byte(`{"codec_type":"audio","codec_name":"aac","channels":2,"channel_layout":"stereo",`+
+		`"side_data_list":[{"side_data_type":"JP Dual-Mono"}]}`), stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	if dualMono, err := stream.IsDualMono(); err != nil || !dualMono {
+		t.Errorf("Expected JP Dual-Mono side data to be dual mono, got %v (%v)", dualMono, err)
+	}
+}
+
+func Test_GetAllDisplayMatrices(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"side_data_list":[`+
+		`{"side_data_type":"Display Matrix","rotation":-90},`+
+		`{"side_data_type":"Stereo 3D","type":"side by side"},`+
+		`{"side_data_type":"Display Matrix","rotation":180}]}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+
+	matrices, err := stream.SideDataList.GetAllDisplayMatrices()
+	if err != nil {
+		t.Fatalf("Error getting display matrices: %v", err)
+	}
+	if len(matrices) != 2 || matrices[0].Rotation != -90 || matrices[1].Rotation != 180 {
+		t.Errorf("Unexpected display matrices %+v", matrices)
+	}
+	if first, err := stream.SideDataList.GetDisplayMatrix(); err != nil || first.Rotation != -90 {
+		t.Errorf("Expected the first display matrix, got %+v (%v)", first, err)
+	}
+
+	if _, err = (SideDataList{}).GetAllDisplayMatrices(); !errors.Is(err, ErrSideDataNotFound) {
+		t.Errorf("Expected ErrSideDataNotFound, got %v", err)
+	}
+}
+
+func Test_SideDataTypes(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"side_data_list":[`+
+		`{"side_data_type":"Display Matrix","rotation":-90},`+
+		`{"side_data_type":"CPB properties","max_bitrate":0}]}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	types := stream.SideDataList.Types()
+	if len(types) != 2 || types[0] != SideDataTypeDisplayMatrix || types[1] != "CPB properties" {
+		t.Errorf("Unexpected side data types %v", types)
+	}
+
+	raw, ok := stream.SideDataList.GetByType("CPB properties")
+	if !ok || string(raw) != `{"side_data_type":"CPB properties","max_bitrate":0}` {
+		t.Errorf("Unexpected raw side data %s", raw)
+	}
+	if _, ok = stream.SideDataList.GetByType(SideDataTypeStereo3D); ok {
+		t.Errorf("Expected no stereo 3D side data")
+	}
+}
+
+func Test_IsAmbisonic(t *testing.T) {
+	stream := &Stream{CodecType: "audio", Channels: 4, ChannelLayout: "ambisonic 1"}
+	if !stream.IsAmbisonic() {
+		t.Errorf("Expected first order ambisonic layout to be detected")
+	}
+	stream = &Stream{CodecType: "audio", Channels: 11, ChannelLayout: "ambisonic 2+stereo"}
+	if !stream.IsAmbisonic() {
+		t.Errorf("Expected ambisonic layout with extra channels to be detected")
+	}
+	stream = &Stream{CodecType: "audio", Channels: 4, ChannelLayout: "quad"}
+	if stream.IsAmbisonic() {
+		t.Errorf("Expected quad not to be ambisonic")
+	}
+}
+
+func Test_AudioServiceType(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"codec_type":"audio","side_data_list":[`+
+		`{"side_data_type":"Audio Service Type","service_type":5}]}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	serviceType, err := stream.SideDataList.GetAudioServiceType()
+	if err != nil {
+		t.Fatalf("Error getting audio service type: %v", err)
+	}
+	if serviceType.ServiceType != AudioServiceTypeCommentary {
+		t.Errorf("Expected commentary service type, got %d", serviceType.ServiceType)
+	}
+}
+
+func Test_SubtitleKind(t *testing.T) {
+	tests := []struct {
+		stream      Stream
+		image, text bool
+	}{
+		{Stream{CodecType: "subtitle", CodecName: "hdmv_pgs_subtitle"}, true, false},
+		{Stream{CodecType: "subtitle", CodecName: "dvd_subtitle"}, true, false},
+		{Stream{CodecType: "subtitle", CodecName: "subrip"}, false, true},
+		{Stream{CodecType: "subtitle", CodecName: "mov_text"}, false, true},
+		{Stream{CodecType: "subtitle", CodecName: "dvb_teletext"}, false, false},
+		{Stream{CodecType: "video", CodecName: "text"}, false, false},
+	}
+	for _, test := range tests {
+		if test.stream.IsImageSubtitle() != test.image || test.stream.IsTextSubtitle() != test.text {
+			t.Errorf("Expected %s %s to be image %v and text %v", test.stream.CodecType, test.stream.CodecName,
+				test.image, test.text)
+		}
+	}
+}
+
+func Test_HasExtradata(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"codec_type":"video","codec_name":"h264","extradata_size":46}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	if stream.ExtradataSize != 46 || !stream.HasExtradata() {
+		t.Errorf("Expected 46 bytes of extradata, got %d", stream.ExtradataSize)
+	}
+
+	stream.ExtradataSize = 0
+	if stream.HasExtradata() {
+		t.Errorf("Expected no extradata")
+	}
+}
+
+func Test_IsTelemetry(t *testing.T) {
+	tests := []struct {
+		stream    Stream
+		telemetry bool
+	}{
+		{Stream{CodecType: "data", CodecTagString: "gpmd", TagList: Tags{"handler_name": "\tGoPro MET"}}, true},
+		{Stream{CodecType: "data", CodecTagString: "tmcd", TagList: Tags{"handler_name": "GoPro TCD"}}, false},
+		{Stream{CodecType: "data", CodecTagString: "djmd"}, true},
+		{Stream{CodecType: "data", TagList: Tags{"handler_name": "DJI meta"}}, true},
+		{Stream{CodecType: "video", TagList: Tags{"handler_name": "GoPro AVC"}}, false},
+	}
+	for _, test := range tests {
+		if test.stream.IsTelemetry() != test.telemetry {
+			t.Errorf("Expected telemetry %v for %s stream %s with handler %q", test.telemetry,
+				test.stream.CodecType, test.stream.CodecTagString, test.stream.HandlerName())
+		}
+	}
+}
+
+func Test_MusicTags(t *testing.T) {
+	format := &Format{
+		TagList: Tags{
+			"TITLE":        "Song",
+			"artist":       "Artist",
+			"ALBUM":        "Album",
+			"album_artist": "Various Artists",
+			"GENRE":        "Jazz",
+			"DATE":         "2019-05-03",
+		},
+	}
+	if format.Title() != "Song" || format.Artist() != "Artist" || format.Album() != "Album" ||
+		format.AlbumArtist() != "Various Artists" || format.Genre() != "Jazz" {
+		t.Errorf("Unexpected music tags %s, %s, %s, %s, %s", format.Title(), format.Artist(), format.Album(),
+			format.AlbumArtist(), format.Genre())
+	}
+	if year, err := format.Year(); err != nil || year != 2019 {
+		t.Errorf("Expected year 2019, got %d (%v)", year, err)
+	}
+
+	format.TagList = Tags{"TYER": "1999"}
+	if year, err := format.Year(); err != nil || year != 1999 {
+		t.Errorf("Expected year 1999 from TYER, got %d (%v)", year, err)
+	}
+
+	format.TagList = Tags{"date": "May"}
+	if _, err := format.Year(); err == nil {
+		t.Errorf("Expected an error for an invalid year")
+	}
+
+	format.TagList = nil
+	if _, err := format.Year(); !errors.Is(err, ErrTagNotFound) {
+		t.Errorf("Expected ErrTagNotFound without tags, got %v", err)
+	}
+}
+
+func Test_Location(t *testing.T) {
+	tests := []struct {
+		location string
+		lat, lon float64
+		ok       bool
+	}{
+		{"+37.7749-122.4194/", 37.7749, -122.4194, true},
+		{"+52.3676+004.9041+002.500/", 52.3676, 4.9041, true},
+		{"-3352+15112/", -33.866667, 151.2, true},
+		{"+401213.1-0750015.1/", 40.203639, -75.004194, true},
+		{"+95.0000+010.0000/", 0, 0, false},
+		{"37.7749,-122.4194", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, test := range tests {
+		data := &ProbeData{Format: &Format{TagList: Tags{"location": test.location}}}
+		lat, lon, ok := data.Location()
+		if ok != test.ok {
+			t.Errorf("Expected ok %v for %q, got %v", test.ok, test.location, ok)
+			continue
+		}
+		if math.Abs(lat-test.lat) > 1e-6 || math.Abs(lon-test.lon) > 1e-6 {
+			t.Errorf("Expected %f,%f for %q, got %f,%f", test.lat, test.lon, test.location, lat, lon)
+		}
+	}
+}
+
+func Test_RotationClockwise(t *testing.T) {
+	displayMatrix := func(rotation int) SideDataList {
+		return SideDataList{
+			{SideDataBase: SideDataBase{Type: SideDataTypeDisplayMatrix}, Data: &SideDataDisplayMatrix{Rotation: rotation}},
+		}
+	}
+	tests := []struct {
+		stream   Stream
+		rotation int
+	}{
+		{Stream{}, 0},
+		{Stream{SideDataList: displayMatrix(-180)}, 180},
+		{Stream{SideDataList: displayMatrix(-90)}, 90},
+		{Stream{SideDataList: displayMatrix(90)}, 270},
+		{Stream{TagList: Tags{"rotate": "90"}}, 90},
+		{Stream{TagList: Tags{"rotate": "-90"}}, 270},
+	}
+	for i, test := range tests {
+		if rotation := test.stream.RotationClockwise(); rotation != test.rotation {
+			t.Errorf("Test %d: expected rotation %d, got %d", i, test.rotation, rotation)
+		}
+	}
+}
+
+func Test_IsConstantBitRate(t *testing.T) {
+	stream := &Stream{CodecName: "pcm_s16le"}
+	if cbr, err := stream.IsConstantBitRate(); err != nil || !cbr {
+		t.Errorf("Expected PCM to be constant bit rate, error: %v", err)
+	}
+
+	stream = &Stream{
+		CodecName: "ac3",
+		BitRate:   "448000",
+		TagList:   Tags{"NUMBER_OF_BYTES-eng": "5600000", "DURATION-eng": "00:01:40.000000000"},
+	}
+	if cbr, err := stream.IsConstantBitRate(); err != nil || !cbr {
+		t.Errorf("Expected matching bit rates to be constant bit rate, error: %v", err)
+	}
+
+	stream.BitRate = "320000"
+	if cbr, err := stream.IsConstantBitRate(); err != nil || cbr {
+		t.Errorf("Expected differing bit rates to be variable bit rate, error: %v", err)
+	}
+
+	stream.TagList = Tags{"BPS-eng": "320000"}
+	if cbr, err := stream.IsConstantBitRate(); err != nil || !cbr {
+		t.Errorf("Expected a bit rate matching the BPS tag to be constant bit rate, error: %v", err)
+	}
+
+	stream.TagList = nil
+	if _, err := stream.IsConstantBitRate(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without statistics tags, got %v", err)
+	}
+
+	stream = &Stream{CodecName: "aac", TagList: Tags{"BPS": "128000"}}
+	if _, err := stream.IsConstantBitRate(); !errors.Is(err, ErrFieldNotFound) {
+		t.Errorf("Expected ErrFieldNotFound without a reported bit rate, got %v", err)
+	}
+}
+
+func Test_BitRateMode(t *testing.T) {
+	tests := []struct {
+		stream   Stream
+		expected string
+	}{
+		{Stream{CodecType: "audio", CodecName: "pcm_s24le"}, "CBR"},
+		{Stream{CodecType: "audio", CodecName: "flac"}, "VBR"},
+		{Stream{CodecType: "audio", CodecName: "opus", BitRate: "128000"}, "VBR"},
+		{Stream{CodecType: "audio", CodecName: "ac3", BitRate: "448000"}, "unknown"},
+		{Stream{CodecType: "audio", CodecName: "ac3", BitRate: "448000",
+			TagList: Tags{"NUMBER_OF_BYTES": "5600000", "DURATION": "00:01:40.000000000"}}, "CBR"},
+		{Stream{CodecType: "video", CodecName: "h264", BitRate: "5000000",
+			TagList: Tags{"NUMBER_OF_BYTES": "40000000", "DURATION": "00:01:40.000000000"}}, "VBR"},
+		{Stream{CodecType: "video", CodecName: "h264"}, "unknown"},
+	}
+	for _, test := range tests {
+		if mode := test.stream.BitRateMode(); mode != test.expected {
+			t.Errorf("Expected %s to be %s, got %s", test.stream.CodecName, test.expected, mode)
+		}
+	}
+}
+
+func Test_OptionalFieldsNotAvailable(t *testing.T) {
+	// The output of ffprobe with -show_optional_fields always for a Matroska file without timestamps
+	const output = `{"streams":[{"index":0,"codec_type":"video","codec_name":"h264","time_base":"1/1000",` +
+		`"start_pts":"N/A","start_time":"N/A","duration_ts":"N/A","duration":"N/A","bit_rate":"N/A"}],` +
+		`"format":{"format_name":"matroska,webm","start_time":"N/A","duration":"N/A","size":"N/A"}}`
+	var data ProbeData
+	if err := json.Unmarshal([]byte(output), &data); err != nil {
+		t.Fatalf("Error decoding output with N/A values: %v", err)
+	}
+	if s := data.Streams[0]; s.StartPts != 0 || s.DurationTs != 0 || s.CodecName != "h264" {
+		t.Errorf("Expected N/A timestamps to decode as 0, got %+v", s)
+	}
+	if data.Format.DurationSeconds != 0 || data.Format.FormatName != "matroska,webm" {
+		t.Errorf("Expected N/A duration to decode as 0, got %+v", data.Format)
+	}
+
+	const values = `{"streams":[{"index":0,"start_pts":-1024,"duration_ts":480000}],` +
+		`"format":{"start_time":"-0.021333","duration":"10.005333"}}`
+	data = ProbeData{}
+	if err := json.Unmarshal([]byte(values), &data); err != nil {
+		t.Fatalf("Error decoding output: %v", err)
+	}
+	if s := data.Streams[0]; s.StartPts != -1024 || s.DurationTs != 480000 {
+		t.Errorf("Expected the timestamps to be decoded, got %+v", s)
+	}
+	if data.Format.StartTimeSeconds != -0.021333 || data.Format.DurationSeconds != 10.005333 {
+		t.Errorf("Expected the times to be decoded, got %+v", data.Format)
+	}
+}
+
+func Test_Minimal(t *testing.T) {
+	data := &ProbeData{
+		Format: &Format{FormatName: "mov,mp4,m4a,3gp,3g2,mj2", DurationSeconds: 5.312},
+		Streams: []*Stream{
+			{Index: 0, CodecType: "video", CodecName: "h264", Width: 640, Height: 360},
+			{Index: 1, CodecType: "audio", CodecName: "aac", Channels: 2},
+		},
+	}
+	buf, err := json.Marshal(data.Minimal())
+	if err != nil {
+		t.Fatalf("Error marshalling: %v", err)
+	}
+
+	const expected = `{"duration":5.312,"format_name":"mov,mp4,m4a,3gp,3g2,mj2","streams":[` +
+		`{"index":0,"codec_type":"video","codec_name":"h264","width":640,"height":360},` +
+		`{"index":1,"codec_type":"audio","codec_name":"aac"}]}`
+	if string(buf) != expected {
+		t.Errorf("Unexpected minimal JSON: %s", buf)
+	}
+}
+
+func Test_ExtradataHash(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"codec_type":"video","extradata_size":42,`+
+		`"extradata_hash":"SHA256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae"}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	if stream.ExtradataHash != "SHA256:2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae" {
+		t.Errorf("Unexpected extradata hash: %s", stream.ExtradataHash)
+	}
+}
+
+func Test_ClosedCaptions(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"codec_type":"video","codec_name":"h264","closed_captions":1}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	if !stream.HasClosedCaptions() {
+		t.Errorf("Expected closed captions, got %d", stream.ClosedCaptions)
+	}
+
+	stream = Stream{}
+	err = json.Unmarshal([]byte(`{"codec_type":"video","codec_name":"h264","closed_captions":0}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	if stream.HasClosedCaptions() {
+		t.Errorf("Expected no closed captions, got %d", stream.ClosedCaptions)
+	}
+}
+
+func Test_Refs(t *testing.T) {
+	var stream Stream
+	err := json.Unmarshal([]byte(`{"codec_type":"video","codec_name":"h264","has_b_frames":2,"refs":4}`), &stream)
+	if err != nil {
+		t.Fatalf("Error decoding stream: %v", err)
+	}
+	if stream.Refs != 4 {
+		t.Errorf("Expected 4 reference frames, got %d", stream.Refs)
+	}
+}
@@ -0,0 +1,78 @@
+package ffprobe
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ReplayGainInfo holds the ReplayGain loudness normalization values of a stream. Gains are in dB, peaks are linear
+// sample amplitudes where 1.0 is full scale. Values that are not tagged are zero.
+type ReplayGainInfo struct {
+	TrackGain float64
+	TrackPeak float64
+	AlbumGain float64
+	AlbumPeak float64
+}
+
+// ReplayGain returns the ReplayGain values parsed from the replaygain_* tags of the stream, as written to Vorbis
+// comments and ID3 TXXX frames. The tag names are matched in lower and upper case and gain units like " dB" are
+// ignored. ErrTagNotFound is returned when none of the tags is present. For MP3 and FLAC files ffprobe reports these
+// tags on the format instead, use ProbeData.ReplayGain to read those as well.
+func (s *Stream) ReplayGain() (*ReplayGainInfo, error) {
+	return replayGainFromTags(s.TagList)
+}
+
+// ReplayGain returns the ReplayGain values of the first audio stream, see Stream.ReplayGain. When that stream has no
+// ReplayGain tags, the tags of the format are used, where ffprobe reports the ID3 tags of MP3 files and the Vorbis
+// comments of FLAC files. ErrTagNotFound is returned when neither has any of the tags.
+func (p *ProbeData) ReplayGain() (*ReplayGainInfo, error) {
+	if s := p.FirstAudioStream(); s != nil {
+		info, err := s.ReplayGain()
+		if !errors.Is(err, ErrTagNotFound) {
+			return info, err
+		}
+	}
+	if p.Format == nil {
+		return nil, ErrTagNotFound
+	}
+	return replayGainFromTags(p.Format.TagList)
+}
+
+// replayGainFromTags parses the ReplayGain values from the replaygain_* tags
+func replayGainFromTags(tags Tags) (*ReplayGainInfo, error) {
+	info := &ReplayGainInfo{}
+	fields := []struct {
+		tag string
+		val *float64
+	}{
+		{"replaygain_track_gain", &info.TrackGain},
+		{"replaygain_track_peak", &info.TrackPeak},
+		{"replaygain_album_gain", &info.AlbumGain},
+		{"replaygain_album_peak", &info.AlbumPeak},
+	}
+
+	found := false
+	for _, field := range fields {
+		val, err := tags.GetString(field.tag)
+		if err != nil {
+			val, err = tags.GetString(strings.ToUpper(field.tag))
+		}
+		if err != nil {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		if len(val) > 2 && strings.EqualFold(val[len(val)-2:], "db") {
+			val = strings.TrimSpace(val[:len(val)-2])
+		}
+		*field.val, err = valToFloat64(val)
+		if err != nil {
+			return nil, fmt.Errorf("replaygain tag %s: %w", field.tag, err)
+		}
+		found = true
+	}
+	if !found {
+		return nil, ErrTagNotFound
+	}
+	return info, nil
+}
@@ -0,0 +1,162 @@
+package ffprobe
+
+import (
+	"sort"
+	"strings"
+)
+
+// audioCodecQuality ranks audio codecs from low to high quality for SelectBestAudio, unlisted codecs rank lowest.
+var audioCodecQuality = []string{
+	"mp2", "mp3", "vorbis", "aac", "opus", "ac3", "eac3", "dts", "alac", "flac", "truehd",
+}
+
+// SelectBestAudio returns the best audio stream, or nil if there are no audio streams. Streams are compared by
+// the position of their language in prefLangs first, then by their channel count and lastly by the quality of their
+// codec. Languages are compared case insensitively, streams in a language not in prefLangs rank below those that are.
+// When streams are equal in all these respects, the stream with the lowest index is returned.
+func (p *ProbeData) SelectBestAudio(prefLangs []string) *Stream {
+	var best *Stream
+	for _, s := range p.Streams {
+		if s == nil || s.CodecType != string(StreamAudio) {
+			continue
+		}
+		if best == nil || betterAudio(s, best, prefLangs) {
+			best = s
+		}
+	}
+	return best
+}
+
+// betterAudio returns whether audio stream a is better than audio stream b
+func betterAudio(a, b *Stream, prefLangs []string) bool {
+	langA, langB := languageRank(a, prefLangs), languageRank(b, prefLangs)
+	if langA != langB {
+		return langA < langB
+	}
+	if a.Channels != b.Channels {
+		return a.Channels > b.Channels
+	}
+	qualityA, qualityB := audioCodecRank(a.CodecName), audioCodecRank(b.CodecName)
+	if qualityA != qualityB {
+		return qualityA > qualityB
+	}
+	return a.Index < b.Index
+}
+
+// AudioLanguages returns the sorted set of languages of the audio streams. Languages are normalized to lower case
+// without surrounding whitespace, the undetermined language "und" and streams without a language are left out.
+func (p *ProbeData) AudioLanguages() []string {
+	var langs []string
+	for _, s := range p.Streams {
+		if s == nil || s.CodecType != string(StreamAudio) {
+			continue
+		}
+		lang, _ := s.TagList.GetString("language")
+		lang = strings.ToLower(strings.TrimSpace(lang))
+		if lang == "" || lang == "und" || containsString(langs, lang) {
+			continue
+		}
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// TrackInfo describes a single stream of the media file for listing the available tracks, see TrackSummary.
+type TrackInfo struct {
+	Index    int
+	Type     StreamType
+	Codec    string
+	Language string
+	Channels int // Only set for audio streams
+	Width    int // Only set for video streams
+	Height   int // Only set for video streams
+	Default  bool
+	Forced   bool
+}
+
+// TrackSummary returns a TrackInfo for every stream, in the order of the streams. The language is taken from the
+// language tag as is, and is empty when the stream has no language tag.
+func (p *ProbeData) TrackSummary() []TrackInfo {
+	tracks := make([]TrackInfo, 0, len(p.Streams))
+	for _, s := range p.Streams {
+		if s == nil {
+			continue
+		}
+		track := TrackInfo{
+			Index:   s.Index,
+			Type:    s.Type(),
+			Codec:   s.CodecName,
+			Default: s.Disposition.Default == 1,
+			Forced:  s.Disposition.Forced == 1,
+		}
+		track.Language, _ = s.TagList.GetString("language")
+		switch track.Type {
+		case StreamAudio:
+			track.Channels = s.Channels
+		case StreamVideo:
+			track.Width, track.Height = s.Width, s.Height
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks
+}
+
+// SelectBestSubtitle returns the best subtitle stream, or nil if there are no suitable subtitle streams. When
+// forcedOnly is set, only streams with the forced disposition are considered and nil is returned if there are none.
+// Streams are compared by the position of their language in prefLangs first, then streams with the default disposition
+// are preferred. When forcedOnly is not set, complete subtitles are preferred over forced ones, as forced subtitles
+// usually only cover foreign language parts. Remaining ties are broken by choosing the stream with the lowest index.
+func (p *ProbeData) SelectBestSubtitle(prefLangs []string, forcedOnly bool) *Stream {
+	var best *Stream
+	for _, s := range p.Streams {
+		if s == nil || s.CodecType != string(StreamSubtitle) {
+			continue
+		}
+		if forcedOnly && s.Disposition.Forced == 0 {
+			continue
+		}
+		if best == nil || betterSubtitle(s, best, prefLangs) {
+			best = s
+		}
+	}
+	return best
+}
+
+// betterSubtitle returns whether subtitle stream a is better than subtitle stream b
+func betterSubtitle(a, b *Stream, prefLangs []string) bool {
+	langA, langB := languageRank(a, prefLangs), languageRank(b, prefLangs)
+	if langA != langB {
+		return langA < langB
+	}
+	if a.Disposition.Default != b.Disposition.Default {
+		return a.Disposition.Default > b.Disposition.Default
+	}
+	if a.Disposition.Forced != b.Disposition.Forced {
+		return a.Disposition.Forced < b.Disposition.Forced
+	}
+	return a.Index < b.Index
+}
+
+// languageRank returns the position of the stream language in prefLangs, or len(prefLangs) when it is not present.
+func languageRank(s *Stream, prefLangs []string) int {
+	lang, _ := s.TagList.GetString("language")
+	for i, pref := range prefLangs {
+		if strings.EqualFold(lang, pref) {
+			return i
+		}
+	}
+	return len(prefLangs)
+}
+
+func audioCodecRank(codec string) int {
+	if strings.HasPrefix(codec, "pcm_") {
+		return len(audioCodecQuality)
+	}
+	for i, c := range audioCodecQuality {
+		if c == codec {
+			return i
+		}
+	}
+	return -1
+}
@@ -21,6 +21,26 @@ const (
 	SideDataTypeSkipSamples              = "Skip Samples"
 	SideDataTypeMasteringDisplayMetadata = "Mastering display metadata"
 	SideDataTypeContentLightLevel        = "Content light level metadata"
+	SideDataTypeEncryptionInitInfo       = "Encryption initialization data"
+	SideDataTypeEncryptionInfo           = "Encryption info"
+	SideDataTypeAudioServiceType         = "Audio Service Type"
+	SideDataTypeJPDualMono               = "JP Dual-Mono"
+)
+
+// AudioServiceType is the type of service an audio stream provides, as defined by enum AVAudioServiceType of
+// libavcodec. It is reported by the SideDataAudioServiceType side data.
+type AudioServiceType int
+
+const (
+	AudioServiceTypeMain             AudioServiceType = 0
+	AudioServiceTypeEffects          AudioServiceType = 1
+	AudioServiceTypeVisuallyImpaired AudioServiceType = 2
+	AudioServiceTypeHearingImpaired  AudioServiceType = 3
+	AudioServiceTypeDialogue         AudioServiceType = 4
+	AudioServiceTypeCommentary       AudioServiceType = 5
+	AudioServiceTypeEmergency        AudioServiceType = 6
+	AudioServiceTypeVoiceOver        AudioServiceType = 7
+	AudioServiceTypeKaraoke          AudioServiceType = 8
 )
 
 type SideDataBase struct {
@@ -86,6 +106,12 @@ type SideDataContentLightLevel struct {
 	MaxAverage int `json:"max_average,omitempty"`
 }
 
+// SideDataAudioServiceType represents the audio service type side data.
+type SideDataAudioServiceType struct {
+	SideDataBase
+	ServiceType AudioServiceType `json:"service_type"`
+}
+
 // SideDataUnknown represents an unknown side data.
 type SideDataUnknown Tags
 
@@ -93,6 +119,9 @@ type SideDataUnknown Tags
 type SideData struct {
 	SideDataBase
 	Data interface{} `json:"-"`
+
+	// raw is the JSON the SideData was decoded from
+	raw json.RawMessage
 }
 
 func (sd *SideData) UnmarshalJSON(b []byte) error {
@@ -106,6 +135,7 @@ func (sd *SideData) UnmarshalJSON(b []byte) error {
 	if err := json.Unmarshal(b, aux); err != nil {
 		return err
 	}
+	sd.raw = append(json.RawMessage(nil), b...)
 
 	switch sd.Type {
 	case SideDataTypeDisplayMatrix:
@@ -120,6 +150,8 @@ func (sd *SideData) UnmarshalJSON(b []byte) error {
 		sd.Data = new(SideDataMasteringDisplayMetadata)
 	case SideDataTypeContentLightLevel:
 		sd.Data = new(SideDataContentLightLevel)
+	case SideDataTypeAudioServiceType:
+		sd.Data = new(SideDataAudioServiceType)
 	default:
 		sd.Data = new(SideDataUnknown)
 	}
@@ -183,7 +215,8 @@ func (s SideDataList) FindSideData(sideDataType string) (interface{}, error) {
 }
 
 // GetDisplayMatrix retrieves the DisplayMatrix from the SideData. If the DisplayMatrix is not found or
-// the SideData is of the wrong type, an error is returned.
+// the SideData is of the wrong type, an error is returned. When there are multiple display matrices, the first one
+// is returned, see GetAllDisplayMatrices.
 func (s SideDataList) GetDisplayMatrix() (*SideDataDisplayMatrix, error) {
 	data, found := s.findSideDataByName(SideDataTypeDisplayMatrix)
 	if !found {
@@ -196,6 +229,26 @@ func (s SideDataList) GetDisplayMatrix() (*SideDataDisplayMatrix, error) {
 	return displayMatrix, nil
 }
 
+// GetAllDisplayMatrices retrieves all DisplayMatrix entries from the SideData, in the order ffprobe reported them.
+// If no DisplayMatrix is found, an error is returned.
+func (s SideDataList) GetAllDisplayMatrices() ([]*SideDataDisplayMatrix, error) {
+	var displayMatrices []*SideDataDisplayMatrix
+	for _, sd := range s {
+		if sd.Type != SideDataTypeDisplayMatrix {
+			continue
+		}
+		displayMatrix, ok := sd.Data.(*SideDataDisplayMatrix)
+		if !ok {
+			return nil, ErrSideDataUnexpectedType
+		}
+		displayMatrices = append(displayMatrices, displayMatrix)
+	}
+	if len(displayMatrices) == 0 {
+		return nil, ErrSideDataNotFound
+	}
+	return displayMatrices, nil
+}
+
 // GetStereo3D retrieves the Stereo3D data from the SideData. If the Stereo3D data is not found or
 // the SideData is of the wrong type, an error is returned.
 func (s SideDataList) GetStereo3D() (*SideDataStereo3D, error) {
@@ -266,6 +319,47 @@ func (s SideDataList) GetContentLightLevel() (*SideDataContentLightLevel, error)
 	return contentLightLevel, nil
 }
 
+// GetAudioServiceType retrieves the AudioServiceType from the SideData. If the AudioServiceType is not found or
+// the SideData is of the wrong type, an error is returned.
+func (s SideDataList) GetAudioServiceType() (*SideDataAudioServiceType, error) {
+	data, found := s.findSideDataByName(SideDataTypeAudioServiceType)
+	if !found {
+		return nil, ErrSideDataNotFound
+	}
+	audioServiceType, ok := data.(*SideDataAudioServiceType)
+	if !ok {
+		return nil, ErrSideDataUnexpectedType
+	}
+	return audioServiceType, nil
+}
+
+// GetByType returns the JSON of the first SideData of the given type, which allows decoding side data types that
+// have no typed getter. False is returned when there is no SideData of the type.
+func (s SideDataList) GetByType(sideDataType string) (json.RawMessage, bool) {
+	for _, sd := range s {
+		if sd.Type != sideDataType {
+			continue
+		}
+		if sd.raw != nil {
+			return append(json.RawMessage(nil), sd.raw...), true
+		}
+		// SideData that was not decoded from JSON is marshalled from its data instead
+		raw, err := json.Marshal(sd.Data)
+		return raw, err == nil
+	}
+	return nil, false
+}
+
+// Types returns the side_data_type values of all SideData in the SideDataList, in the order ffprobe reported them.
+// A type occurs multiple times when there are multiple SideData of that type.
+func (s SideDataList) Types() []string {
+	types := make([]string, 0, len(s))
+	for _, sd := range s {
+		types = append(types, sd.Type)
+	}
+	return types
+}
+
 func (s SideDataList) findSideDataByName(sideDataType string) (interface{}, bool) {
 	for _, sd := range s {
 		if sd.Type == sideDataType {
@@ -3,7 +3,9 @@ package ffprobe
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 // ErrTagNotFound is a sentinel error used when a queried tag does not exist
@@ -94,6 +96,16 @@ func valToFloat64(str string) (float64, error) {
 	return val, nil
 }
 
+// getFirstString returns the value of the first of the tags that exists, or an empty string if none exist.
+func (t Tags) getFirstString(tags ...string) string {
+	for _, tag := range tags {
+		if val, err := t.GetString(tag); err == nil {
+			return val
+		}
+	}
+	return ""
+}
+
 // FormatTags is a json data structure to represent format tags
 // Deprecated, use the Tags of TagList instead
 type FormatTags struct {
@@ -113,12 +125,16 @@ func (f *FormatTags) setFrom(tags Tags) {
 // StreamTags is a json data structure to represent stream tags
 // Deprecated, use the Tags of TagList instead
 type StreamTags struct {
-	Rotate       int    `json:"rotate,string,omitempty"`
-	CreationTime string `json:"creation_time,omitempty"`
-	Language     string `json:"language,omitempty"`
-	Title        string `json:"title,omitempty"`
-	Encoder      string `json:"encoder,omitempty"`
-	Location     string `json:"location,omitempty"`
+	Rotate         int    `json:"rotate,string,omitempty"`
+	CreationTime   string `json:"creation_time,omitempty"`
+	Language       string `json:"language,omitempty"`
+	Title          string `json:"title,omitempty"`
+	Encoder        string `json:"encoder,omitempty"`
+	Location       string `json:"location,omitempty"`
+	HandlerName    string `json:"handler_name,omitempty"`
+	BPS            int64  `json:"BPS,string,omitempty"`
+	Duration       string `json:"DURATION,omitempty"`
+	NumberOfFrames int64  `json:"NUMBER_OF_FRAMES,string,omitempty"`
 }
 
 func (s *StreamTags) setFrom(tags Tags) {
@@ -130,4 +146,39 @@ func (s *StreamTags) setFrom(tags Tags) {
 	s.Title, _ = tags.GetString("title")
 	s.Encoder, _ = tags.GetString("encoder")
 	s.Location, _ = tags.GetString("location")
+	s.HandlerName, _ = tags.GetString("handler_name")
+
+	// Matroska statistics tags can have a language suffix, e.g. "BPS-eng"
+	bps, _ := tags.getIntWithSuffix("BPS")
+	s.BPS = bps
+	s.Duration, _ = tags.getStringWithSuffix("DURATION")
+	s.NumberOfFrames, _ = tags.getIntWithSuffix("NUMBER_OF_FRAMES")
+}
+
+// suffixedTag returns the name of the tag, or of a tag with the same name followed by a suffix like "-eng" when the
+// tag itself does not exist. Matroska files written by mkvmerge store their statistics tags with such a suffix.
+func (t Tags) suffixedTag(tag string) string {
+	if _, found := t[tag]; found {
+		return tag
+	}
+
+	names := make([]string, 0, len(t))
+	for name := range t {
+		if strings.HasPrefix(name, tag+"-") {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return tag
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+func (t Tags) getStringWithSuffix(tag string) (string, error) {
+	return t.GetString(t.suffixedTag(tag))
+}
+
+func (t Tags) getIntWithSuffix(tag string) (int64, error) {
+	return t.GetInt(t.suffixedTag(tag))
 }
@@ -0,0 +1,76 @@
+package ffprobe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// VersionReport holds the versions of ffprobe and the ffmpeg libraries it uses, as reported by -show_versions
+type VersionReport struct {
+	ProgramVersion  ProgramVersion   `json:"program_version"`
+	LibraryVersions []LibraryVersion `json:"library_versions"`
+}
+
+// ProgramVersion holds the version and build information of the ffprobe program
+type ProgramVersion struct {
+	Version       string `json:"version"`
+	Copyright     string `json:"copyright"`
+	CompilerIdent string `json:"compiler_ident"`
+	Configuration string `json:"configuration"`
+}
+
+// LibraryVersion holds the version of one of the ffmpeg libraries, like libavcodec or libavformat
+type LibraryVersion struct {
+	Name    string `json:"name"`
+	Major   int    `json:"major"`
+	Minor   int    `json:"minor"`
+	Micro   int    `json:"micro"`
+	Version int    `json:"version"`
+	Ident   string `json:"ident"`
+}
+
+// String returns the version of the library in the major.minor.micro form, e.g. "60.31.102"
+func (l *LibraryVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", l.Major, l.Minor, l.Micro)
+}
+
+// Libraries returns the versions of the libraries by their name, e.g. "libavcodec" to "60.31.102"
+func (v *VersionReport) Libraries() map[string]string {
+	libraries := make(map[string]string, len(v.LibraryVersions))
+	for i := range v.LibraryVersions {
+		libraries[v.LibraryVersions[i].Name] = v.LibraryVersions[i].String()
+	}
+	return libraries
+}
+
+// Versions is used to get the versions of the ffprobe program and the ffmpeg libraries it uses, which is useful for
+// diagnosing codec or format specific issues.
+// This function takes a context to allow killing the ffprobe process if it takes too long or in case of shutdown.
+func Versions(ctx context.Context) (*VersionReport, error) {
+	cmd := exec.CommandContext(ctx, binPath,
+		"-loglevel", "fatal",
+		"-print_format", "json=c=1",
+		"-show_versions",
+	)
+	cmd.SysProcAttr = procAttributes()
+
+	var outputBuf bytes.Buffer
+	var stdErr bytes.Buffer
+
+	cmd.Stdout = &outputBuf
+	cmd.Stderr = &stdErr
+
+	err := cmd.Run()
+	if err != nil {
+		return nil, newExecError(stdErr.String(), err)
+	}
+
+	report := &VersionReport{}
+	err = decodeOutput(outputBuf.Bytes(), report)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ffprobe output: %w", err)
+	}
+	return report, nil
+}